@@ -7,10 +7,46 @@ terraform {
 }
 
 provider "proxmoxve" {
-  base_url      = "https://pmve.example.com:8006"
-  token_id      = "apiuser@pam!proxmoxve_terraform_token"
-  secret        = "token_secret"
-  root_password = "password"
-  totp_seed     = "<generated by Proxmox VE when enabling 2FA>"
-  tls_insecure  = false
+  base_url     = "https://pmve.example.com:8006"
+  token_id     = "apiuser@pam!proxmoxve_terraform_token"
+  secret       = "token_secret"
+  username     = "terraform@pve"
+  password     = "password"
+  totp_seed    = "<generated by Proxmox VE when enabling 2FA>"
+  tls_insecure = false
+
+  # Keep large applies from exhausting the workers of pveproxy.
+  max_concurrent_requests = 4
+  requests_per_second     = 10
+}
+
+# A second cluster, reached through whichever of its nodes answers first.
+provider "proxmoxve" {
+  alias = "dr"
+  base_urls = [
+    "https://pmve-dr1.example.com:8006",
+    "https://pmve-dr2.example.com:8006",
+    "https://pmve-dr3.example.com:8006",
+  ]
+  token_id    = "apiuser@pam!proxmoxve_terraform_token"
+  secret_file = "/run/secrets/proxmoxve_token"
+
+  # The nodes' certificates are issued by the cluster's own CA.
+  ca_cert_file = "pve-root-ca.pem"
+}
+
+# A cluster only reachable from a bastion host, authenticating with the keys
+# of the local SSH agent.
+provider "proxmoxve" {
+  alias    = "lab"
+  base_url = "https://10.0.0.10:8006"
+
+  # Prints {"token_id": "...", "secret": "..."}.
+  credential_process = "vault kv get -format=json -field=data secret/proxmoxve/lab"
+
+  ssh_tunnel {
+    host     = "bastion.example.com"
+    user     = "terraform"
+    host_key = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIGb1Bp6qzC7y1NrIKVfl6KYnW5CY9Dd6PmRJ7T2Q9BlL"
+  }
 }
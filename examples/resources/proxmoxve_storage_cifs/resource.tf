@@ -0,0 +1,14 @@
+variable "smb_password" {
+  type      = string
+  sensitive = true
+}
+
+resource "proxmoxve_storage_cifs" "backups" {
+  name       = "backups"
+  server     = "nas.example.com"
+  share      = "pve"
+  username   = "pve"
+  password   = var.smb_password
+  smbversion = "3"
+  content    = ["backup", "iso"]
+}
@@ -0,0 +1,2 @@
+# Storages are imported by name.
+terraform import proxmoxve_storage_lvm.san san
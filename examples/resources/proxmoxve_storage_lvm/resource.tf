@@ -0,0 +1,9 @@
+# A volume group on an iSCSI LUN, shared by all the nodes of the cluster.
+resource "proxmoxve_storage_lvm" "san" {
+  name       = "san"
+  vgname     = "vg_san"
+  base       = "san-iscsi:0.0.1.scsi-36001405a1b2c3d4e5f60718293a4b5c6"
+  shared     = true
+  saferemove = true
+  content    = ["images", "rootdir"]
+}
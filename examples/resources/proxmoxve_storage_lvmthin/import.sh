@@ -0,0 +1,2 @@
+# Storages are imported by name, e.g. the default local-lvm.
+terraform import proxmoxve_storage_lvmthin.local_lvm local-lvm
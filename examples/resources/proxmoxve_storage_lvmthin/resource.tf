@@ -0,0 +1,7 @@
+# The thin pool created by the installer on every node.
+resource "proxmoxve_storage_lvmthin" "local_lvm" {
+  name     = "local-lvm"
+  vgname   = "pve"
+  thinpool = "data"
+  content  = ["images", "rootdir"]
+}
@@ -0,0 +1,21 @@
+variable "pbs_token_secret" {
+  type      = string
+  sensitive = true
+}
+
+resource "proxmoxve_storage_pbs" "backups" {
+  name           = "pbs"
+  server         = "pbs.example.com"
+  datastore      = "backups"
+  namespace      = "cluster1"
+  username       = "pve@pbs!cluster1"
+  password       = var.pbs_token_secret
+  fingerprint    = "64:d3:ff:3a:50:38:53:5a:9b:f7:50:ce:b2:7d:4e:66:98:c5:0c:d8:30:d9:66:2f:e8:94:b3:c7:35:97:ab:fe"
+  encryption_key = "autogen"
+}
+
+# Backups cannot be restored without the encryption key: keep a copy of it.
+output "pbs_encryption_key" {
+  value     = proxmoxve_storage_pbs.backups.generated_encryption_key
+  sensitive = true
+}
@@ -0,0 +1,2 @@
+# Storages are imported by name, e.g. the default local-zfs of installations on ZFS.
+terraform import proxmoxve_storage_zfspool.local_zfs local-zfs
@@ -0,0 +1,7 @@
+resource "proxmoxve_storage_zfspool" "tank" {
+  name      = "tank"
+  pool      = "tank/pve"
+  blocksize = "16k"
+  sparse    = true
+  content   = ["images", "rootdir"]
+}
@@ -0,0 +1,190 @@
+package provider
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+)
+
+// apiErrorKind classifies the errors returned by the Proxmox VE API.
+type apiErrorKind int
+
+const (
+	apiErrorUnknown apiErrorKind = iota
+	apiErrorNotFound
+	apiErrorPermissionDenied
+	apiErrorLocked
+	apiErrorDigestMismatch
+	apiErrorValidation
+)
+
+// apiError is an error response of the Proxmox VE API. pveproxy sends the error
+// message as the HTTP reason phrase and, for parameter verification failures,
+// the message of each offending parameter under `errors` in the body.
+type apiError struct {
+	Kind       apiErrorKind
+	StatusCode int
+	Message    string
+	Params     map[string]string
+}
+
+func (e *apiError) Error() string {
+	msg := fmt.Sprintf("%d %s", e.StatusCode, e.Message)
+	for _, param := range e.sortedParams() {
+		msg += fmt.Sprintf("\n%s: %s", param, e.Params[param])
+	}
+	return msg
+}
+
+func (e *apiError) sortedParams() []string {
+	params := make([]string, 0, len(e.Params))
+	for param := range e.Params {
+		params = append(params, param)
+	}
+	sort.Strings(params)
+	return params
+}
+
+// newAPIError builds an apiError from the status line and body of a response.
+func newAPIError(statusCode int, message string, body []byte) *apiError {
+	var content struct {
+		Errors map[string]string `json:"errors"`
+	}
+	_ = json.Unmarshal(body, &content)
+
+	e := &apiError{StatusCode: statusCode, Message: strings.TrimSpace(message), Params: content.Errors}
+	e.Kind = classifyAPIError(e)
+	return e
+}
+
+func classifyAPIError(e *apiError) apiErrorKind {
+	msg := strings.ToLower(e.Message)
+	switch {
+	case len(e.Params) > 0 && allParamsNotFound(e.Params):
+		// e.g. `{"cidr": "no such IP/Network"}` when reading an IPSet entry.
+		return apiErrorNotFound
+	case e.StatusCode == http.StatusBadRequest || len(e.Params) > 0:
+		return apiErrorValidation
+	case e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden:
+		return apiErrorPermissionDenied
+	case e.StatusCode == http.StatusNotFound:
+		return apiErrorNotFound
+	case strings.Contains(msg, "detected modified configuration"):
+		return apiErrorDigestMismatch
+	case strings.Contains(msg, "can't lock file"),
+		strings.Contains(msg, "cfs-lock"),
+		strings.Contains(msg, "is locked"):
+		return apiErrorLocked
+	case e.StatusCode != http.StatusInternalServerError:
+		return apiErrorUnknown
+	case strings.Contains(msg, "does not exist"),
+		strings.Contains(msg, "no such "),
+		strings.Contains(msg, "not found"):
+		// The object missing may not be the one requested: isNotFound
+		// checks that the message names it.
+		return apiErrorNotFound
+	}
+	return apiErrorUnknown
+}
+
+func allParamsNotFound(params map[string]string) bool {
+	for _, msg := range params {
+		if !strings.HasPrefix(strings.ToLower(msg), "no such ") {
+			return false
+		}
+	}
+	return true
+}
+
+// apiClientErrorRegexp matches the errors built by the API client from a failed
+// response: the HTTP status followed by the response body.
+var apiClientErrorRegexp = regexp.MustCompile(`(?s)^(\d{3}) ([^\n]*?)(?:\n|: |$)(.*)$`)
+
+// asAPIError returns the apiError carried by err, or nil if err is not an
+// error response of the API (e.g. a network failure).
+func asAPIError(err error) *apiError {
+	if err == nil {
+		return nil
+	}
+	var e *apiError
+	if errors.As(err, &e) {
+		return e
+	}
+	m := apiClientErrorRegexp.FindStringSubmatch(err.Error())
+	if m == nil {
+		return nil
+	}
+	statusCode, _ := strconv.Atoi(m[1])
+	return newAPIError(statusCode, m[2], []byte(m[3]))
+}
+
+func isAPIErrorKind(err error, kind apiErrorKind) bool {
+	e := asAPIError(err)
+	return e != nil && e.Kind == kind
+}
+
+// isNotFound reports whether err means that the requested object, called by
+// one of names, does not exist, i.e. it was deleted outside of Terraform. The
+// message of the error must name the object: another object missing, e.g. one
+// referenced by a parameter, does not mean the requested one is gone.
+func isNotFound(err error, names ...string) bool {
+	e := asAPIError(err)
+	if e == nil || e.Kind != apiErrorNotFound {
+		return false
+	}
+	if len(e.Params) > 0 {
+		// The parameters are those of the request, e.g. the CIDR of an IPSet
+		// entry.
+		return true
+	}
+	for _, name := range names {
+		if name != "" && strings.Contains(e.Message, "'"+name+"'") {
+			return true
+		}
+	}
+	return false
+}
+
+// addAPIErrorDiagnostics adds err to diags. Parameter verification failures
+// are reported against the attribute of each parameter: its name with dashes
+// replaced by underscores, or the attribute given in params when they differ.
+func addAPIErrorDiagnostics(diags *diag.Diagnostics, summary string, err error, params map[string]string) {
+	e := asAPIError(err)
+	if e == nil {
+		diags.AddError(summary, err.Error())
+		return
+	}
+
+	switch e.Kind {
+	case apiErrorValidation:
+		if len(e.Params) == 0 {
+			diags.AddError(summary, e.Error())
+			return
+		}
+		for _, param := range e.sortedParams() {
+			attr, ok := params[param]
+			if !ok {
+				attr = strings.ReplaceAll(param, "-", "_")
+			}
+			diags.AddAttributeError(path.Root(attr), summary, fmt.Sprintf("Proxmox VE rejected parameter %q: %s", param, e.Params[param]))
+		}
+	case apiErrorNotFound:
+		diags.AddError(summary, e.Error()+"\n\nThe object no longer exists on the server. It may have been deleted outside of Terraform.")
+	case apiErrorPermissionDenied:
+		diags.AddError(summary, e.Error()+"\n\nCheck that the configured credentials have the privileges required by this operation.")
+	case apiErrorLocked:
+		diags.AddError(summary, e.Error()+"\n\nThe object is locked by another task. Try again once the task has finished.")
+	case apiErrorDigestMismatch:
+		diags.AddError(summary, e.Error()+"\n\nThe configuration was modified concurrently. Refresh and try again.")
+	default:
+		diags.AddError(summary, e.Error())
+	}
+}
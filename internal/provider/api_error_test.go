@@ -0,0 +1,77 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAsAPIError(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		kind apiErrorKind
+	}{
+		{errors.New("500 storage 'foo' does not exist\n{\"data\":null}"), apiErrorNotFound},
+		{errors.New("500 no such alias 'foo'\n{\"data\":null}"), apiErrorNotFound},
+		{errors.New("500 ACME plugin 'foo' does not exist\n"), apiErrorNotFound},
+		{errors.New("404 Not Found\n"), apiErrorNotFound},
+		{errors.New("403 Permission check failed (/storage, Datastore.Allocate)\n{\"data\":null}"), apiErrorPermissionDenied},
+		{errors.New("401 authentication failure: {\"data\":null}"), apiErrorPermissionDenied},
+		{errors.New("500 cfs-lock 'file-storage_cfg' error: got lock request timeout\n"), apiErrorLocked},
+		{errors.New("500 can't lock file '/var/lock/qemu-server/lock-100.conf' - got timeout\n"), apiErrorLocked},
+		{errors.New("500 detected modified configuration - file changed by other user? Try again.\n"), apiErrorDigestMismatch},
+		{errors.New("400 Parameter verification failed.\n{\"data\":null,\"errors\":{\"path\":\"property is missing and it is not optional\"}}"), apiErrorValidation},
+		{errors.New("500 unexpected error\n"), apiErrorUnknown},
+		{errors.New("501 Method 'GET /foo' not found\n"), apiErrorUnknown},
+	} {
+		t.Run(tc.err.Error(), func(t *testing.T) {
+			e := asAPIError(tc.err)
+			require.NotNil(t, e)
+			assert.Equal(t, tc.kind, e.Kind)
+		})
+	}
+
+	assert.Nil(t, asAPIError(errors.New("dial tcp 127.0.0.1:8006: connect: connection refused")))
+	assert.Nil(t, asAPIError(nil))
+
+	wrapped := fmt.Errorf("wrapped: %w", &apiError{Kind: apiErrorLocked, StatusCode: 500, Message: "locked"})
+	assert.True(t, isAPIErrorKind(wrapped, apiErrorLocked))
+}
+
+func TestIsNotFound(t *testing.T) {
+	assert.True(t, isNotFound(errors.New("500 storage 'foo' does not exist\n"), "foo"))
+	assert.True(t, isNotFound(errors.New("500 no such IPSet 'servers'\n"), "servers", "10.0.0.0/8"))
+	// The parameters are those of the request.
+	assert.True(t, isNotFound(errors.New("400 Parameter verification failed.\n{\"errors\":{\"cidr\":\"no such IP/Network\"}}"), "10.0.0.0/8"))
+
+	// Another object is missing, e.g. one referenced by a parameter.
+	assert.False(t, isNotFound(errors.New("500 storage 'foo' does not exist\n"), "bar"))
+	assert.False(t, isNotFound(errors.New("500 storage 'foobar' does not exist\n"), "foo"))
+	assert.False(t, isNotFound(errors.New("404 Not Found\n"), "foo"))
+	assert.False(t, isNotFound(errors.New("500 unexpected error\n"), "foo"))
+	assert.False(t, isNotFound(nil, "foo"))
+}
+
+func TestAddAPIErrorDiagnostics(t *testing.T) {
+	var diags diag.Diagnostics
+	err := errors.New(`400 Parameter verification failed.` + "\n" + `{"data":null,"errors":{"storage":"invalid format","prune-backups":"invalid format"}}`)
+	addAPIErrorDiagnostics(&diags, "Error creating storage_dir", err, map[string]string{"storage": "name"})
+
+	require.Len(t, diags, 2)
+	for i, attr := range []string{"prune_backups", "name"} {
+		withPath, ok := diags[i].(diag.DiagnosticWithPath)
+		require.True(t, ok)
+		assert.Equal(t, path.Root(attr), withPath.Path())
+		assert.Equal(t, "Error creating storage_dir", diags[i].Summary())
+	}
+
+	diags = nil
+	addAPIErrorDiagnostics(&diags, "Error reading storage_dir", errors.New("connection refused"), nil)
+	require.Len(t, diags, 1)
+	assert.Equal(t, "connection refused", diags[0].Detail())
+}
@@ -0,0 +1,102 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	proxmox "github.com/c10l/proxmoxve-client-go/api"
+	version "github.com/c10l/proxmoxve-client-go/api/version"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// tlsErrorHint tells how to trust the certificate of an API server.
+const tlsErrorHint = "Set `ca_cert_file` or `ca_cert_pem` to the CA certificate of the cluster (`/etc/pve/pve-root-ca.pem` unless replaced), " +
+	"or pin the certificate with `tls_fingerprint_sha256`. `tls_insecure = true` disables verification altogether, which is not recommended."
+
+// checkConnection reads /version once with the credential resources use by
+// default, so that an unreachable server or rejected credentials are reported
+// when the provider is configured, rather than by whichever resource comes
+// first. Nothing is checked without credentials: the resources needing them
+// report it. The release read is recorded in d for checkVersion.
+//
+// Any credential can read /version, so the permissions of the credential are
+// then read to warn about a credential without any privilege, e.g. a
+// privilege-separated API token whose permissions were granted to its user.
+func checkConnection(ctx context.Context, d *providerData, diags *diag.Diagnostics) {
+	method := d.authPreference
+	if method == "" {
+		method = authToken
+	}
+	client, err := d.newClient(ctx, method, diags)
+	if errors.Is(err, errMissingCredentials) {
+		method = authTicket
+		if d.authPreference == authTicket {
+			method = authToken
+		}
+		client, err = d.newClient(ctx, method, diags)
+		if errors.Is(err, errMissingCredentials) {
+			return
+		}
+	}
+
+	var v *version.GetResponse
+	if err == nil {
+		v, err = version.GetRequest{Client: withContext(ctx, client)}.Do()
+	}
+	if err != nil {
+		summary, detail := describeConnectionError(method, err)
+		diags.AddError(summary, detail)
+		return
+	}
+	d.release = v.Release
+	tflog.Info(ctx, "Connected to Proxmox VE", map[string]interface{}{"version": v.Version, "release": v.Release, "auth": string(method)})
+
+	perms, err := d.permissions.get(ctx, client)
+	if err != nil {
+		tflog.Warn(ctx, "Unable to read the permissions of the Proxmox VE credential", map[string]interface{}{"error": err.Error()})
+		return
+	}
+	if perms.empty() {
+		diags.AddWarning(
+			"Insufficient privileges",
+			fmt.Sprintf("The credentials of %s are valid, but have no privileges on Proxmox VE, so resources cannot be managed with them. "+
+				"A privilege-separated API token needs permissions of its own, granted on the token rather than its user.", credentialName(method, client)),
+		)
+	}
+}
+
+// credentialName names the credential of client, authenticating with method:
+// the ID of its API token if any.
+func credentialName(method authMethod, client *proxmox.Client) string {
+	if client.APIToken != nil {
+		return fmt.Sprintf("the API token `%s`", client.APIToken.TokenID)
+	}
+	return method.description()
+}
+
+// describeConnectionError returns the summary and detail of the diagnostic
+// reporting that the API could not be called with method.
+func describeConnectionError(method authMethod, err error) (string, string) {
+	if isTLSError(err) {
+		return "Unable to verify the certificate of Proxmox VE", fmt.Sprintf("%s\n\n%s", err, tlsErrorHint)
+	}
+
+	var apiErr *apiError
+	for e := err; e != nil && apiErr == nil; e = errors.Unwrap(e) {
+		apiErr = asAPIError(e)
+	}
+	switch {
+	case apiErr == nil:
+		return "Unable to connect to Proxmox VE", err.Error()
+	case apiErr.StatusCode == http.StatusUnauthorized:
+		return "Proxmox VE rejected the credentials",
+			fmt.Sprintf("Authenticating with %s failed. Check that they are set, and have not expired or been revoked.\n\n%s", method.description(), err)
+	case apiErr.StatusCode == http.StatusNotFound || apiErr.StatusCode == http.StatusNotImplemented:
+		return "Proxmox VE API not found",
+			fmt.Sprintf("The base URL does not point to a Proxmox VE API server. It should be the address of the web interface, e.g. https://pmve.example.com:8006\n\n%s", err)
+	}
+	return "Unable to connect to Proxmox VE", err.Error()
+}
@@ -0,0 +1,102 @@
+package provider
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"terraform-provider-proxmoxve/internal/pvemock"
+
+	proxmox "github.com/c10l/proxmoxve-client-go/api"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testConnectionDiags returns the diagnostics of checkConnection with a token
+// client of s authenticated with secret, sending its requests through
+// httpTransport.
+func testConnectionDiags(t *testing.T, s *pvemock.Server, secret string, httpTransport http.RoundTripper) diag.Diagnostics {
+	var diags diag.Diagnostics
+	checkConnection(context.Background(), &providerData{
+		tokenClient: func(context.Context, *diag.Diagnostics) (*proxmox.Client, error) {
+			client := testTransportClient(t, s, httpTransport)
+			client.APIToken.Secret = secret
+			return client, nil
+		},
+		ticketClient: func(context.Context, *diag.Diagnostics) (*proxmox.Client, error) {
+			return nil, fmt.Errorf("%w: password cannot be empty", errMissingCredentials)
+		},
+	}, &diags)
+	return diags
+}
+
+func TestCheckConnection(t *testing.T) {
+	s := pvemock.New()
+	t.Cleanup(s.Close)
+	insecure := newHTTPTransport(&tls.Config{InsecureSkipVerify: true}, nil, nil)
+
+	assert.False(t, testConnectionDiags(t, s, s.Secret, insecure).HasError())
+
+	diags := testConnectionDiags(t, s, s.Secret, newHTTPTransport(&tls.Config{}, nil, nil))
+	require.True(t, diags.HasError())
+	assert.Equal(t, "Unable to verify the certificate of Proxmox VE", diags[0].Summary())
+	assert.Contains(t, diags[0].Detail(), "tls_fingerprint_sha256")
+
+	diags = testConnectionDiags(t, s, "revoked", insecure)
+	require.True(t, diags.HasError())
+	assert.Equal(t, "Proxmox VE rejected the credentials", diags[0].Summary())
+	assert.Contains(t, diags[0].Detail(), "`token_id` and `secret`")
+
+	// Any credential can read /version, even without any privilege.
+	s.SetPermissions(map[string]map[string]int{})
+	diags = testConnectionDiags(t, s, s.Secret, insecure)
+	assert.False(t, diags.HasError())
+	require.Len(t, diags.Warnings(), 1)
+	assert.Equal(t, "Insufficient privileges", diags[0].Summary())
+	assert.Contains(t, diags[0].Detail(), "the API token `"+s.TokenID+"`")
+	s.SetPermissions(nil)
+
+	// Nothing is checked without credentials.
+	diags = nil
+	missing := func(context.Context, *diag.Diagnostics) (*proxmox.Client, error) {
+		return nil, fmt.Errorf("%w: secret cannot be empty", errMissingCredentials)
+	}
+	checkConnection(context.Background(), &providerData{tokenClient: missing, ticketClient: missing}, &diags)
+	assert.False(t, diags.HasError())
+}
+
+func TestDescribeConnectionError(t *testing.T) {
+	for status, want := range map[int]string{
+		http.StatusUnauthorized:   "Proxmox VE rejected the credentials",
+		http.StatusNotFound:       "Proxmox VE API not found",
+		http.StatusNotImplemented: "Proxmox VE API not found",
+		http.StatusBadGateway:     "Unable to connect to Proxmox VE",
+	} {
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		}))
+		client, err := newClient(s.URL, http.DefaultTransport)
+		require.NoError(t, err)
+		_, err = client.Get(&client.APIurl)
+		s.Close()
+		require.Error(t, err)
+
+		summary, _ := describeConnectionError(authToken, err)
+		assert.Equal(t, want, summary, status)
+		// The errors of the ticket client wrap the API error.
+		summary, _ = describeConnectionError(authTicket, fmt.Errorf("unable to create client:\n\n%w", err))
+		assert.Equal(t, want, summary, status)
+	}
+
+	summary, detail := describeConnectionError(authToken, &fingerprintMismatchError{Fingerprint: "AB:CD"})
+	assert.Equal(t, "Unable to verify the certificate of Proxmox VE", summary)
+	assert.Contains(t, detail, "AB:CD")
+
+	summary, _ = describeConnectionError(authToken, errors.New("connection refused"))
+	assert.Equal(t, "Unable to connect to Proxmox VE", summary)
+}
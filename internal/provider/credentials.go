@@ -0,0 +1,146 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// credentialProcessTimeout bounds the run of credential_process.
+const credentialProcessTimeout = 1 * time.Minute
+
+// processCredentials are printed as a JSON object by credential_process. Every
+// field is optional.
+type processCredentials struct {
+	TokenID      string `json:"token_id"`
+	Secret       string `json:"secret"`
+	Username     string `json:"username"`
+	Password     string `json:"password"`
+	RootPassword string `json:"root_password"`
+	TOTPSeed     string `json:"totp_seed"`
+	YubicoOTP    string `json:"yubico_otp"`
+	RecoveryKey  string `json:"recovery_key"`
+}
+
+// resolveCredentials sets the credential attributes of data left unset from
+// secret_file, root_password_file and credential_process, or their
+// environment variables. The environment variables of the credentials
+// themselves only apply to what is still unset afterwards.
+func resolveCredentials(ctx context.Context, data *ProxmoxVEProviderModel, diags *diag.Diagnostics) {
+	data.Secret = fromFile(data.Secret, data.SecretFile, "secret", "PROXMOXVE_SECRET_FILE", diags)
+	data.RootPassword = fromFile(data.RootPassword, data.RootPasswordFile, "root_password", "PROXMOXVE_ROOT_PASSWORD_FILE", diags)
+	if diags.HasError() {
+		return
+	}
+
+	command := stringOrEnv(data.CredentialProcess, "PROXMOXVE_CREDENTIAL_PROCESS")
+	if command == "" {
+		return
+	}
+	creds, err := runCredentialProcess(ctx, command)
+	if err != nil {
+		diags.AddAttributeError(path.Root("credential_process"), "Unable to run credential_process", err.Error())
+		return
+	}
+	for _, c := range []struct {
+		attr  *types.String
+		value string
+	}{
+		{&data.TokenID, creds.TokenID},
+		{&data.Secret, creds.Secret},
+		{&data.Username, creds.Username},
+		{&data.Password, creds.Password},
+		{&data.RootPassword, creds.RootPassword},
+		{&data.TOTPSeed, creds.TOTPSeed},
+		{&data.YubicoOTP, creds.YubicoOTP},
+		{&data.RecoveryKey, creds.RecoveryKey},
+	} {
+		if c.attr.IsNull() && c.value != "" {
+			*c.attr = types.StringValue(c.value)
+		}
+	}
+}
+
+// fromFile returns attr, or the content of the file set by fileAttr or its
+// environment variable env if attr is not set.
+func fromFile(attr, fileAttr types.String, name, env string, diags *diag.Diagnostics) types.String {
+	if !attr.IsNull() && !fileAttr.IsNull() {
+		diags.AddAttributeError(path.Root(name+"_file"), fmt.Sprintf("Conflicting %s and %s_file", name, name), fmt.Sprintf("Only one of %s and %s_file can be set.", name, name))
+		return attr
+	}
+	file := stringOrEnv(fileAttr, env)
+	if !attr.IsNull() || file == "" {
+		return attr
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		diags.AddAttributeError(path.Root(name+"_file"), "Unable to read "+name+"_file", err.Error())
+		return attr
+	}
+	value := strings.TrimRight(string(content), "\r\n")
+	if value == "" {
+		diags.AddAttributeError(path.Root(name+"_file"), "Empty "+name+"_file", fmt.Sprintf("%s is empty.", file))
+		return attr
+	}
+	return types.StringValue(value)
+}
+
+// runCredentialProcess runs command with the shell and decodes the
+// credentials it prints. Its output is never logged.
+func runCredentialProcess(ctx context.Context, command string) (processCredentials, error) {
+	ctx, cancel := context.WithTimeout(ctx, credentialProcessTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd.exe", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "/bin/sh", "-c", command)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			err = fmt.Errorf("timed out after %s", credentialProcessTimeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%w:\n\n%s", err, msg)
+		}
+		return processCredentials{}, err
+	}
+
+	var creds processCredentials
+	decoder := json.NewDecoder(&stdout)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&creds); err != nil {
+		// The output may hold credentials, so it is not quoted.
+		return processCredentials{}, fmt.Errorf("the output is not a JSON object of credentials: %s", jsonErrorReason(err))
+	}
+	return creds, nil
+}
+
+// jsonErrorReason describes err without quoting the input it failed on.
+func jsonErrorReason(err error) string {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		return fmt.Sprintf("invalid JSON at offset %d", syntaxErr.Offset)
+	case errors.As(err, &typeErr):
+		return fmt.Sprintf("%s must be a %s", typeErr.Field, typeErr.Type)
+	}
+	// e.g. `json: unknown field "token"`, naming the field but not its value.
+	return err.Error()
+}
@@ -0,0 +1,106 @@
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCredentialsModel returns a provider configuration without credentials.
+func testCredentialsModel() ProxmoxVEProviderModel {
+	return ProxmoxVEProviderModel{
+		TokenID:           types.StringNull(),
+		Secret:            types.StringNull(),
+		Username:          types.StringNull(),
+		Password:          types.StringNull(),
+		RootPassword:      types.StringNull(),
+		TOTPSeed:          types.StringNull(),
+		YubicoOTP:         types.StringNull(),
+		RecoveryKey:       types.StringNull(),
+		SecretFile:        types.StringNull(),
+		RootPasswordFile:  types.StringNull(),
+		CredentialProcess: types.StringNull(),
+	}
+}
+
+func TestFromFile(t *testing.T) {
+	t.Setenv("PROXMOXVE_SECRET_FILE", "")
+	dir := t.TempDir()
+	file := filepath.Join(dir, "secret")
+	require.NoError(t, os.WriteFile(file, []byte("s3cret\n"), 0o600))
+
+	var diags diag.Diagnostics
+	value := fromFile(types.StringNull(), types.StringValue(file), "secret", "PROXMOXVE_SECRET_FILE", &diags)
+	require.False(t, diags.HasError())
+	assert.Equal(t, "s3cret", value.ValueString())
+
+	// The attribute itself takes precedence over the environment variable.
+	t.Setenv("PROXMOXVE_SECRET_FILE", file)
+	value = fromFile(types.StringValue("inline"), types.StringNull(), "secret", "PROXMOXVE_SECRET_FILE", &diags)
+	require.False(t, diags.HasError())
+	assert.Equal(t, "inline", value.ValueString())
+	value = fromFile(types.StringNull(), types.StringNull(), "secret", "PROXMOXVE_SECRET_FILE", &diags)
+	require.False(t, diags.HasError())
+	assert.Equal(t, "s3cret", value.ValueString())
+
+	fromFile(types.StringValue("inline"), types.StringValue(file), "secret", "PROXMOXVE_SECRET_FILE", &diags)
+	assert.True(t, diags.HasError())
+
+	diags = nil
+	fromFile(types.StringNull(), types.StringValue(filepath.Join(dir, "missing")), "secret", "PROXMOXVE_SECRET_FILE", &diags)
+	assert.True(t, diags.HasError())
+
+	diags = nil
+	require.NoError(t, os.WriteFile(file, []byte("\n"), 0o600))
+	fromFile(types.StringNull(), types.StringValue(file), "secret", "PROXMOXVE_SECRET_FILE", &diags)
+	assert.True(t, diags.HasError())
+}
+
+func TestRunCredentialProcess(t *testing.T) {
+	creds, err := runCredentialProcess(context.Background(), `printf '{"token_id": "terraform@pve!ci", "secret": "s3cret"}'`)
+	require.NoError(t, err)
+	assert.Equal(t, processCredentials{TokenID: "terraform@pve!ci", Secret: "s3cret"}, creds)
+
+	_, err = runCredentialProcess(context.Background(), `echo "vault is sealed" >&2; exit 3`)
+	assert.ErrorContains(t, err, "exit status 3:\n\nvault is sealed")
+
+	// The output is not quoted in errors, as it may hold credentials.
+	_, err = runCredentialProcess(context.Background(), `echo s3cret`)
+	assert.ErrorContains(t, err, "invalid JSON")
+	assert.NotContains(t, err.Error(), "s3cret")
+
+	_, err = runCredentialProcess(context.Background(), `echo '{"secret": 42}'`)
+	assert.ErrorContains(t, err, "secret must be a string")
+	assert.NotContains(t, err.Error(), "42")
+
+	_, err = runCredentialProcess(context.Background(), `echo '{"token": "s3cret"}'`)
+	assert.ErrorContains(t, err, `unknown field "token"`)
+	assert.NotContains(t, err.Error(), "s3cret")
+}
+
+func TestResolveCredentials(t *testing.T) {
+	t.Setenv("PROXMOXVE_SECRET_FILE", "")
+	t.Setenv("PROXMOXVE_ROOT_PASSWORD_FILE", "")
+	t.Setenv("PROXMOXVE_CREDENTIAL_PROCESS", `echo '{"token_id": "terraform@pve!ci", "secret": "from-process", "root_password": "root"}'`)
+
+	var diags diag.Diagnostics
+	data := testCredentialsModel()
+	data.Secret = types.StringValue("inline")
+	resolveCredentials(context.Background(), &data, &diags)
+	require.False(t, diags.HasError())
+	assert.Equal(t, "terraform@pve!ci", data.TokenID.ValueString())
+	assert.Equal(t, "inline", data.Secret.ValueString())
+	assert.Equal(t, "root", data.RootPassword.ValueString())
+	assert.True(t, data.Username.IsNull())
+
+	data = testCredentialsModel()
+	data.CredentialProcess = types.StringValue("exit 1")
+	resolveCredentials(context.Background(), &data, &diags)
+	assert.True(t, diags.HasError())
+}
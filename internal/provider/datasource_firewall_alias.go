@@ -61,31 +61,12 @@ func (d *FirewallAliasDataSource) Schema(ctx context.Context, req datasource.Sch
 }
 
 func (d *FirewallAliasDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
-	// Prevent panic if the provider has not been configured.
-	if req.ProviderData == nil {
+	providerData := configureProviderData(req.ProviderData, &resp.Diagnostics)
+	if providerData == nil {
 		return
 	}
 
-	clientFunc, ok := req.ProviderData.(map[string]getClientFunc)["token"]
-
-	if !ok {
-		resp.Diagnostics.AddError(
-			"Unexpected Data Source Configure Type",
-			fmt.Sprintf("Expected *proxmox.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
-		)
-
-		return
-	}
-
-	client, err := clientFunc()
-	if err != nil {
-		resp.Diagnostics.AddError(
-			"Unable to instantiate client",
-			err.Error(),
-		)
-	}
-
-	d.client = client
+	d.client = providerData.client(ctx, d.typeName(), types.StringNull(), authToken, &resp.Diagnostics)
 }
 
 func (d *FirewallAliasDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
@@ -95,7 +76,7 @@ func (d *FirewallAliasDataSource) Read(ctx context.Context, req datasource.ReadR
 		return
 	}
 
-	alias, err := aliases.ItemGetRequest{Client: d.client, Name: data.Name.ValueString()}.Get()
+	alias, err := aliases.ItemGetRequest{Client: withContext(ctx, d.client), Name: data.Name.ValueString()}.Get()
 	if err != nil {
 		resp.Diagnostics.AddError(fmt.Sprintf("Error reading firewall_alias.%s", data.Name.ValueString()), err.Error())
 		return
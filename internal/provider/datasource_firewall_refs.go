@@ -2,7 +2,6 @@ package provider
 
 import (
 	"context"
-	"fmt"
 	"time"
 
 	proxmox "github.com/c10l/proxmoxve-client-go/api"
@@ -37,8 +36,10 @@ type firewallRefsDataSourceDataRefModel struct {
 	Comment types.String `tfsdk:"comment"`
 }
 
+func (d *FirewallRefsDataSource) typeName() string { return "firewall_refs" }
+
 func (d *FirewallRefsDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
-	resp.TypeName = req.ProviderTypeName + "_firewall_refs"
+	resp.TypeName = req.ProviderTypeName + "_" + d.typeName()
 }
 
 func (d *FirewallRefsDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
@@ -74,31 +75,12 @@ func (d *FirewallRefsDataSource) Schema(ctx context.Context, req datasource.Sche
 }
 
 func (d *FirewallRefsDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
-	// Prevent panic if the provider has not been configured.
-	if req.ProviderData == nil {
+	providerData := configureProviderData(req.ProviderData, &resp.Diagnostics)
+	if providerData == nil {
 		return
 	}
 
-	clientFunc, ok := req.ProviderData.(map[string]getClientFunc)["token"]
-
-	if !ok {
-		resp.Diagnostics.AddError(
-			"Unexpected Data Source Configure Type",
-			fmt.Sprintf("Expected *proxmox.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
-		)
-
-		return
-	}
-
-	client, err := clientFunc()
-	if err != nil {
-		resp.Diagnostics.AddError(
-			"Unable to instantiate client",
-			err.Error(),
-		)
-	}
-
-	d.client = client
+	d.client = providerData.client(ctx, d.typeName(), types.StringNull(), authToken, &resp.Diagnostics)
 }
 
 func (d *FirewallRefsDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
@@ -111,7 +93,7 @@ func (d *FirewallRefsDataSource) Read(ctx context.Context, req datasource.ReadRe
 		return
 	}
 
-	firewallRefs, err := refs.GetRequest{Client: d.client}.Do()
+	firewallRefs, err := refs.GetRequest{Client: withContext(ctx, d.client)}.Do()
 	if err != nil {
 		resp.Diagnostics.AddError("Error retrieving firewall_refs", err.Error())
 		return
@@ -2,10 +2,8 @@ package provider
 
 import (
 	"context"
-	"fmt"
 
 	proxmox "github.com/c10l/proxmoxve-client-go/api"
-	"github.com/c10l/proxmoxve-client-go/api/storage"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
 	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
 	"github.com/hashicorp/terraform-plugin-framework/diag"
@@ -37,8 +35,10 @@ type StorageDataSourceModel struct {
 	Preallocation types.String `tfsdk:"preallocation"`
 }
 
+func (d *StorageDataSource) typeName() string { return "storage" }
+
 func (d *StorageDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
-	resp.TypeName = req.ProviderTypeName + "_storage"
+	resp.TypeName = req.ProviderTypeName + "_" + d.typeName()
 }
 
 func (d *StorageDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
@@ -82,31 +82,12 @@ func (d *StorageDataSource) Schema(ctx context.Context, req datasource.SchemaReq
 }
 
 func (d *StorageDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
-	// Prevent panic if the provider has not been configured.
-	if req.ProviderData == nil {
+	providerData := configureProviderData(req.ProviderData, &resp.Diagnostics)
+	if providerData == nil {
 		return
 	}
 
-	clientFunc, ok := req.ProviderData.(map[string]getClientFunc)["token"]
-
-	if !ok {
-		resp.Diagnostics.AddError(
-			"Unexpected Data Source Configure Type",
-			fmt.Sprintf("Expected *proxmox.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
-		)
-
-		return
-	}
-
-	client, err := clientFunc()
-	if err != nil {
-		resp.Diagnostics.AddError(
-			"Unable to instantiate client",
-			err.Error(),
-		)
-	}
-
-	d.client = client
+	d.client = providerData.client(ctx, d.typeName(), types.StringNull(), authToken, &resp.Diagnostics)
 }
 
 func (d *StorageDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
@@ -119,7 +100,7 @@ func (d *StorageDataSource) Read(ctx context.Context, req datasource.ReadRequest
 		return
 	}
 
-	storage, err := storage.ItemGetRequest{Client: d.client, Storage: data.Name.ValueString()}.Get()
+	storage, err := readStorage(ctx, d.client, data.Name.ValueString())
 	if err != nil {
 		resp.Diagnostics.AddError("Error retrieving storage", err.Error())
 		return
@@ -2,7 +2,6 @@ package provider
 
 import (
 	"context"
-	"fmt"
 
 	proxmox "github.com/c10l/proxmoxve-client-go/api"
 	version "github.com/c10l/proxmoxve-client-go/api/version"
@@ -33,8 +32,10 @@ type VersionDataSourceModel struct {
 	Console types.String `tfsdk:"console"`
 }
 
+func (d *VersionDataSource) typeName() string { return "version" }
+
 func (d *VersionDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
-	resp.TypeName = req.ProviderTypeName + "_version"
+	resp.TypeName = req.ProviderTypeName + "_" + d.typeName()
 }
 
 // Version data source schema
@@ -66,31 +67,12 @@ func (d *VersionDataSource) Schema(ctx context.Context, req datasource.SchemaReq
 }
 
 func (d *VersionDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
-	// Prevent panic if the provider has not been configured.
-	if req.ProviderData == nil {
+	providerData := configureProviderData(req.ProviderData, &resp.Diagnostics)
+	if providerData == nil {
 		return
 	}
 
-	clientFunc, ok := req.ProviderData.(map[string]getClientFunc)["token"]
-
-	if !ok {
-		resp.Diagnostics.AddError(
-			"Unexpected Data Source Configure Type",
-			fmt.Sprintf("Expected *proxmox.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
-		)
-
-		return
-	}
-
-	client, err := clientFunc()
-	if err != nil {
-		resp.Diagnostics.AddError(
-			"Unable to instantiate client",
-			err.Error(),
-		)
-	}
-
-	d.client = client
+	d.client = providerData.client(ctx, d.typeName(), types.StringNull(), authToken, &resp.Diagnostics)
 }
 
 // Read -
@@ -104,7 +86,7 @@ func (d *VersionDataSource) Read(ctx context.Context, req datasource.ReadRequest
 		return
 	}
 
-	version, err := version.GetRequest{Client: d.client}.Do()
+	version, err := version.GetRequest{Client: withContext(ctx, d.client)}.Do()
 	if err != nil {
 		resp.Diagnostics.AddError("Error retrieving version", err.Error())
 		return
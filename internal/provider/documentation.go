@@ -2,7 +2,10 @@ package provider
 
 const providerMarkdownDescription = "" +
 	"Use the ProxmoxVE provider to manage Proxmox Virtual Environment configuration, reasources, virtual machines etc. on a compatible PMVE cluster or standalone server. You must configure the provider with API credentials before using it." +
-	"<p />The following environment variables can be set as a fallback for any omitted attributes in the provider declaration: `PROXMOXVE_BASE_URL`, `PROXMOXVE_TOKEN_ID`, `PROXMOXVE_SECRET`, `PROXMOXVE_ROOT_PASSWORD`, `PROXMOXVE_TOTPSEED`, `PROXMOXVE_TLS_INSECURE`." +
-	"<p />**NOTE:** `base_url` attribute is always required. Additionally, most API endpoints require `token_id` and `secret`. Other API endpoints require `root_password`, and if 2FA is enabled for the `root` user, `totp_seed` must also be informed."
+	"<p />The following environment variables can be set as a fallback for any omitted attributes in the provider declaration: `PROXMOXVE_BASE_URL`, `PROXMOXVE_BASE_URLS`, `PROXMOXVE_TOKEN_ID`, `PROXMOXVE_SECRET`, `PROXMOXVE_SECRET_FILE`, `PROXMOXVE_USERNAME`, `PROXMOXVE_PASSWORD`, `PROXMOXVE_REALM`, `PROXMOXVE_ROOT_PASSWORD`, `PROXMOXVE_ROOT_PASSWORD_FILE`, `PROXMOXVE_CREDENTIAL_PROCESS`, `PROXMOXVE_TOTPSEED`, `PROXMOXVE_YUBICO_OTP`, `PROXMOXVE_RECOVERY_KEY`, `PROXMOXVE_TLS_INSECURE`, `PROXMOXVE_CA_CERT`, `PROXMOXVE_TLS_FINGERPRINT_SHA256`, `PROXMOXVE_PROXY_URL`, `PROXMOXVE_SSH_HOST`, `PROXMOXVE_SSH_USER`, `PROXMOXVE_SSH_PRIVATE_KEY`, `PROXMOXVE_SSH_HOST_KEY`, `PROXMOXVE_AUTH_PREFERENCE`, `PROXMOXVE_MAX_RETRIES`, `PROXMOXVE_RETRY_BACKOFF_MIN`, `PROXMOXVE_RETRY_BACKOFF_MAX`, `PROXMOXVE_MAX_CONCURRENT_REQUESTS`, `PROXMOXVE_REQUESTS_PER_SECOND`." +
+	"<p />**NOTE:** one of the `base_url` or `base_urls` attributes is always required. Additionally, most API endpoints require `token_id` and `secret`. Other API endpoints require a ticket, acquired with `username` and `password` (or `root_password` for the `root@pam` user), and if 2FA is enabled for that user, one of `totp_seed`, `yubico_otp` or `recovery_key` must also be informed. The provider reads `/version` and `/access/permissions` once when configured, with the API token if set or the ticket otherwise, to report an unreachable server, rejected credentials or credentials without any privilege up front." +
+	"<p />Every API request and response is logged to the `api` subsystem: method, path, status and latency at `DEBUG`, headers, parameters and bodies at `TRACE`, with credentials masked. Its level follows `TF_LOG_PROVIDER_PROXMOXVE`, and can be set apart with `TF_LOG_PROVIDER_PROXMOXVE_API`." +
+	"<p />Before a resource is created, updated or deleted, the plan checks that its credential has the privileges needed, as read once from `/access/permissions`, and reports those missing with the path and role to grant them." +
+	"<p />The release of Proxmox VE read from `/version` is also checked by the plan: resources and attributes unsupported by the cluster, e.g. `proxmoxve_storage_btrfs` before 7.0, are reported instead of failing the apply."
 
-const docRequiresRoot = "<p />**NOTE:** This resource requires the provider attribute `root_password` or the environment variable `PROXMOXVE_ROOT_PASSWORD` set."
+const docTicketByDefault = "<p />**NOTE:** This resource authenticates with a ticket by default: the provider attributes `username` and `password`, or `root_password`, or their environment variables must be set. Set `auth = \"token\"` to manage it with a sufficiently privileged API token instead."
@@ -0,0 +1,233 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	proxmox "github.com/c10l/proxmoxve-client-go/api"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// endpointHealthCheckTimeout bounds the health check of a single endpoint.
+const endpointHealthCheckTimeout = 5 * time.Second
+
+// endpointPool holds the base URLs of the nodes of a cluster. All clients of
+// the provider send their requests to the same endpoint, which only changes
+// when it stops accepting connections.
+type endpointPool struct {
+	mu      sync.Mutex
+	urls    []*url.URL
+	current int
+
+	// check returns why an endpoint is unhealthy, or nil.
+	check func(ctx context.Context, u *url.URL) error
+}
+
+// newEndpointPool parses baseURLs, in order of preference.
+func newEndpointPool(baseURLs []string, httpTransport http.RoundTripper) (*endpointPool, error) {
+	if len(baseURLs) == 0 {
+		return nil, errors.New("at least one URL is required")
+	}
+	p := &endpointPool{}
+	for _, baseURL := range baseURLs {
+		u, err := parseBaseURL(baseURL)
+		if err != nil {
+			return nil, err
+		}
+		p.urls = append(p.urls, u)
+	}
+
+	httpClient := &http.Client{Timeout: endpointHealthCheckTimeout, Transport: httpTransport}
+	p.check = func(ctx context.Context, u *url.URL) error {
+		return checkEndpoint(ctx, httpClient, u)
+	}
+	return p, nil
+}
+
+// parseBaseURL parses the base URL of an API server, which the API path is
+// appended to.
+func parseBaseURL(baseURL string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimRight(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a valid URL: %w", baseURL, err)
+	}
+	switch {
+	case (u.Scheme != "https" && u.Scheme != "http") || u.Host == "":
+		return nil, fmt.Errorf("%q is not an absolute http(s) URL, e.g. https://pmve.example.com:8006", baseURL)
+	case strings.HasSuffix(u.Path, "/api2/json"):
+		return nil, fmt.Errorf("%q must not include the API path, e.g. %s", baseURL, strings.TrimSuffix(u.String(), "/api2/json"))
+	case u.User != nil || u.RawQuery != "" || u.Fragment != "":
+		return nil, fmt.Errorf("%q cannot include credentials, a query or a fragment", baseURL)
+	}
+	return u, nil
+}
+
+// baseURLValidator checks that base_url, or each of base_urls, is the base
+// URL of an API server.
+type baseURLValidator struct{}
+
+func (v baseURLValidator) Description(ctx context.Context) string {
+	return "value must be an absolute http(s) URL, e.g. https://pmve.example.com:8006"
+}
+
+func (v baseURLValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v baseURLValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+	if _, err := parseBaseURL(req.ConfigValue.ValueString()); err != nil {
+		resp.Diagnostics.AddAttributeError(req.Path, "Invalid base URL", err.Error())
+	}
+}
+
+func (v baseURLValidator) ValidateList(ctx context.Context, req validator.ListRequest, resp *validator.ListResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+	for i, elem := range req.ConfigValue.Elements() {
+		s, ok := elem.(types.String)
+		if !ok || s.IsNull() || s.IsUnknown() {
+			continue
+		}
+		if _, err := parseBaseURL(s.ValueString()); err != nil {
+			resp.Diagnostics.AddAttributeError(req.Path.AtListIndex(i), "Invalid base URL", err.Error())
+		}
+	}
+}
+
+// checkEndpoint reports whether the API server at u answers. Any response
+// short of a server error will do, as the request is not authenticated.
+func checkEndpoint(ctx context.Context, httpClient *http.Client, u *url.URL) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String()+"/api2/json/version", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		return errors.New(resp.Status)
+	}
+	return nil
+}
+
+// endpoint returns the endpoint currently in use.
+func (p *endpointPool) endpoint() *url.URL {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.urls[p.current]
+}
+
+// selectEndpoint checks the endpoints in order of preference, and sticks to the
+// first healthy one. It returns why each endpoint before it was skipped, or an
+// error if none is healthy. A single endpoint is used without being checked.
+func (p *endpointPool) selectEndpoint(ctx context.Context) (*url.URL, []error, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.urls) == 1 {
+		return p.urls[0], nil, nil
+	}
+	return p.selectFrom(ctx, 0)
+}
+
+// failover moves to the next healthy endpoint after failed, unless another
+// request already did. The endpoints before failed are checked last.
+func (p *endpointPool) failover(ctx context.Context, failed *url.URL) (*url.URL, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.urls[p.current] != failed {
+		return p.urls[p.current], nil
+	}
+	if len(p.urls) == 1 {
+		return nil, errors.New("no other endpoint configured")
+	}
+	u, _, err := p.selectFrom(ctx, p.current+1)
+	return u, err
+}
+
+func (p *endpointPool) selectFrom(ctx context.Context, start int) (*url.URL, []error, error) {
+	var skipped []error
+	for i := 0; i < len(p.urls); i++ {
+		idx := (start + i) % len(p.urls)
+		u := p.urls[idx]
+		if err := p.check(ctx, u); err != nil {
+			skipped = append(skipped, fmt.Errorf("%s: %w", u, err))
+			continue
+		}
+		p.current = idx
+		return u, skipped, nil
+	}
+	return nil, skipped, errors.New("none of the Proxmox VE endpoints is reachable")
+}
+
+// failoverTransport sends requests to the endpoint currently in use by pool,
+// and moves to another endpoint when a connection to it cannot be opened.
+// Requests which could not be sent at all are safe to send again whatever
+// their method.
+type failoverTransport struct {
+	next http.RoundTripper
+	pool *endpointPool
+
+	// base is the endpoint the client builds its request URLs from.
+	base *url.URL
+}
+
+func (t *failoverTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	u := t.pool.endpoint()
+	resp, err := t.next.RoundTrip(t.rewrite(req, u))
+	if err == nil || ctx.Err() != nil || !isDialError(err) || !rewindBody(req) {
+		return resp, err
+	}
+
+	next, ferr := t.pool.failover(ctx, u)
+	if ferr != nil {
+		return nil, err
+	}
+	tflog.Warn(ctx, "Proxmox VE endpoint unreachable, failing over", map[string]interface{}{
+		"endpoint": u.String(),
+		"next":     next.String(),
+		"error":    err.Error(),
+	})
+	return t.next.RoundTrip(t.rewrite(req, next))
+}
+
+// rewrite returns req sent to u instead of the base endpoint.
+func (t *failoverTransport) rewrite(req *http.Request, u *url.URL) *http.Request {
+	if u == t.base {
+		return req
+	}
+	r := req.Clone(req.Context())
+	r.URL.Scheme = u.Scheme
+	r.URL.Host = u.Host
+	r.URL.Path = u.Path + strings.TrimPrefix(req.URL.Path, t.base.Path)
+	r.URL.RawPath = ""
+	r.Host = ""
+	return r
+}
+
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// withEndpoints makes c send its requests to the endpoint currently in use by
+// pool. c must have been created with the URL returned by pool.endpoint().
+func withEndpoints(c *proxmox.Client, pool *endpointPool) *proxmox.Client {
+	c.HTTPClient.Transport = &failoverTransport{next: transport(c.HTTPClient), pool: pool, base: pool.endpoint()}
+	return c
+}
@@ -0,0 +1,126 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	proxmox "github.com/c10l/proxmoxve-client-go/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testEndpoint serves /version as the node called name.
+func testEndpoint(t *testing.T, name string) *httptest.Server {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api2/json/version" {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]string{"repoid": name}})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// testDownEndpoint returns the URL of a server which is not listening anymore.
+func testDownEndpoint() string {
+	s := httptest.NewServer(http.NotFoundHandler())
+	s.Close()
+	return s.URL
+}
+
+func TestNewEndpointPool(t *testing.T) {
+	_, err := newEndpointPool(nil, http.DefaultTransport)
+	assert.Error(t, err)
+
+	_, err = newEndpointPool([]string{"https://pve1:8006", "pve2:8006"}, http.DefaultTransport)
+	assert.ErrorContains(t, err, `"pve2:8006" is not an absolute http(s) URL`)
+
+	pool, err := newEndpointPool([]string{"https://pve1:8006/"}, http.DefaultTransport)
+	require.NoError(t, err)
+	assert.Equal(t, "https://pve1:8006", pool.endpoint().String())
+
+	// A single endpoint is not checked.
+	u, skipped, err := pool.selectEndpoint(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, skipped)
+	assert.Equal(t, "https://pve1:8006", u.String())
+}
+
+func TestParseBaseURL(t *testing.T) {
+	for baseURL, want := range map[string]string{
+		"https://pve1:8006":            "",
+		"http://pve1:8006/":            "",
+		"https://pve1:8006/pve":        "",
+		"pve1:8006":                    "is not an absolute http(s) URL",
+		"ftp://pve1":                   "is not an absolute http(s) URL",
+		"https://":                     "is not an absolute http(s) URL",
+		"https://pve1:8006/api2/json/": "must not include the API path, e.g. https://pve1:8006",
+		"https://root:pw@pve1:8006":    "cannot include credentials",
+		"https://pve1:8006?node=pve1":  "cannot include credentials, a query",
+		"https://pve1:8006/#v1:0":      "cannot include credentials, a query or a fragment",
+		"https://pve1:port":            "is not a valid URL",
+	} {
+		_, err := parseBaseURL(baseURL)
+		if want == "" {
+			assert.NoError(t, err, baseURL)
+		} else {
+			assert.ErrorContains(t, err, want, baseURL)
+		}
+	}
+}
+
+func TestEndpointPoolSelectEndpoint(t *testing.T) {
+	down := testDownEndpoint()
+	up := testEndpoint(t, "pve2")
+
+	pool, err := newEndpointPool([]string{down, up.URL}, http.DefaultTransport)
+	require.NoError(t, err)
+	u, skipped, err := pool.selectEndpoint(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, up.URL, u.String())
+	require.Len(t, skipped, 1)
+	assert.Contains(t, skipped[0].Error(), down)
+	assert.Equal(t, up.URL, pool.endpoint().String())
+
+	pool, err = newEndpointPool([]string{down, testDownEndpoint()}, http.DefaultTransport)
+	require.NoError(t, err)
+	_, skipped, err = pool.selectEndpoint(context.Background())
+	assert.Error(t, err)
+	assert.Len(t, skipped, 2)
+}
+
+func TestFailoverTransport(t *testing.T) {
+	pve1 := testEndpoint(t, "pve1")
+	pve2 := testEndpoint(t, "pve2")
+
+	pool, err := newEndpointPool([]string{pve1.URL, pve2.URL}, http.DefaultTransport)
+	require.NoError(t, err)
+	_, _, err = pool.selectEndpoint(context.Background())
+	require.NoError(t, err)
+
+	client, err := proxmox.NewAPITokenClient(pool.endpoint().String(), "root@pam!test", "secret", false)
+	require.NoError(t, err)
+	client = withEndpoints(client, pool)
+
+	repoID := func() string {
+		var version struct {
+			RepoID string `json:"repoid"`
+		}
+		u := client.APIurl
+		u.Path += "/version"
+		require.NoError(t, getJSON(client, &u, &version))
+		return version.RepoID
+	}
+	assert.Equal(t, "pve1", repoID())
+
+	pve1.Close()
+	assert.Equal(t, "pve2", repoID())
+	assert.Equal(t, pve2.URL, pool.endpoint().String())
+
+	// Selection sticks to the new endpoint.
+	assert.Equal(t, "pve2", repoID())
+}
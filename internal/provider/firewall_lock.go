@@ -0,0 +1,116 @@
+package provider
+
+import (
+	"context"
+	"sync"
+
+	proxmox "github.com/c10l/proxmoxve-client-go/api"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// clusterFirewall is the scope of the cluster-level firewall configuration,
+// cluster.fw, which holds all cluster aliases, IPSets and security groups.
+const clusterFirewall = "/cluster/firewall"
+
+// firewallLocks serializes the changes made by the provider to a firewall
+// configuration, keyed by scope. Proxmox VE rewrites the whole configuration
+// file on every change, so concurrent changes would otherwise keep failing
+// their digest check.
+var firewallLocks = &scopeLocks{locks: map[string]chan struct{}{}}
+
+type scopeLocks struct {
+	mu    sync.Mutex
+	locks map[string]chan struct{}
+}
+
+// lock waits until scope is free and takes it, returning the function which
+// releases it. Waiting is bounded by the deadline of ctx.
+func (l *scopeLocks) lock(ctx context.Context, scope string) (func(), error) {
+	l.mu.Lock()
+	ch, ok := l.locks[scope]
+	if !ok {
+		ch = make(chan struct{}, 1)
+		l.locks[scope] = ch
+	}
+	l.mu.Unlock()
+
+	select {
+	case ch <- struct{}{}:
+		return func() { <-ch }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// firewallListEntry is an entry of a firewall list, e.g. an alias of
+// {scope}/aliases, which carries the digest of the list.
+type firewallListEntry struct {
+	Digest string `json:"digest"`
+}
+
+// firewallListDigest returns the digest of the firewall list at path, or ""
+// if the list is empty, as no entry carries it then.
+func firewallListDigest(client *proxmox.Client, path string) (string, error) {
+	listURL := client.APIurl
+	listURL.Path += path
+	var entries []firewallListEntry
+	if err := getJSON(client, &listURL, &entries); err != nil {
+		return "", err
+	}
+	if len(entries) == 0 {
+		return "", nil
+	}
+	return entries[0].Digest, nil
+}
+
+// mutateFirewall applies a change to the firewall configuration of scope.
+// Changes to the same scope are serialized within the provider, as they all
+// rewrite the same file.
+//
+// Proxmox VE checks the digest sent along a change against that of the list
+// changed, e.g. {scope}/aliases for an alias or {scope}/ipset/{name} for an
+// IPSet entry. f is passed the current digest of the list at path, to send
+// unless empty. If the list was modified by someone else in between, f is
+// called again with the new digest, as many times as the client retries
+// transient errors. path is empty for the requests taking no digest, e.g. the
+// creation of an alias: f is then passed "".
+func mutateFirewall(ctx context.Context, client *proxmox.Client, scope, path string, f func(digest string) error) error {
+	unlock, err := firewallLocks.lock(ctx, scope)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	c := withContext(ctx, client)
+	policy := clientRetryPolicy(client)
+	for attempt := 0; ; attempt++ {
+		digest := ""
+		if path != "" {
+			if digest, err = firewallListDigest(c, path); err != nil {
+				return err
+			}
+		}
+
+		err := f(digest)
+		if attempt >= policy.MaxRetries || !isAPIErrorKind(err, apiErrorDigestMismatch) {
+			return err
+		}
+		tflog.Debug(ctx, "Firewall configuration modified concurrently, retrying", map[string]interface{}{
+			"scope":   scope,
+			"path":    path,
+			"attempt": attempt + 1,
+		})
+
+		if err := policy.sleep(ctx, attempt); err != nil {
+			return err
+		}
+	}
+}
+
+// digestParam returns digest to send along a request, or nil if it is empty.
+func digestParam(digest string) *string {
+	if digest == "" {
+		return nil
+	}
+	return &digest
+}
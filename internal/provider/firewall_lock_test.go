@@ -0,0 +1,102 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"terraform-provider-proxmoxve/internal/pvemock"
+
+	proxmox "github.com/c10l/proxmoxve-client-go/api"
+	"github.com/c10l/proxmoxve-client-go/api/cluster/firewall/aliases"
+	"github.com/c10l/proxmoxve-client-go/api/cluster/firewall/ipset"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testFirewallClient(t *testing.T, maxRetries int) *proxmox.Client {
+	s := pvemock.New()
+	t.Cleanup(s.Close)
+	client, err := proxmox.NewAPITokenClient(s.URL, s.TokenID, s.Secret, true)
+	require.NoError(t, err)
+	return withRetries(client, retryPolicy{MaxRetries: maxRetries, BackoffMin: time.Millisecond, BackoffMax: time.Millisecond})
+}
+
+func TestMutateFirewallRetriesDigestMismatch(t *testing.T) {
+	client := testFirewallClient(t, 2)
+	require.NoError(t, aliases.PostRequest{Client: client, Name: "a", CIDR: "10.0.0.1"}.Post())
+
+	calls := 0
+	err := mutateFirewall(context.Background(), client, clusterFirewall, clusterFirewall+"/aliases", func(digest string) error {
+		calls++
+		if calls == 1 {
+			// Someone else changes the configuration after its digest was read.
+			require.NoError(t, aliases.PostRequest{Client: client, Name: "b", CIDR: "10.0.0.2"}.Post())
+		}
+		return aliases.ItemPutRequest{Client: client, Name: "a", CIDR: "10.0.0.3", Digest: &digest}.Put()
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+
+	err = mutateFirewall(context.Background(), client, clusterFirewall, clusterFirewall+"/aliases", func(string) error {
+		stale := "0000000000000000000000000000000000000000"
+		return aliases.ItemPutRequest{Client: client, Name: "a", CIDR: "10.0.0.4", Digest: &stale}.Put()
+	})
+	assert.True(t, isAPIErrorKind(err, apiErrorDigestMismatch))
+
+	// Changes to other lists do not affect the digest of the aliases.
+	calls = 0
+	err = mutateFirewall(context.Background(), client, clusterFirewall, clusterFirewall+"/aliases", func(digest string) error {
+		calls++
+		require.NoError(t, ipset.PostRequest{Client: client, Name: "s"}.Post())
+		return aliases.ItemPutRequest{Client: client, Name: "a", CIDR: "10.0.0.5", Digest: &digest}.Put()
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestMutateFirewallSerializes(t *testing.T) {
+	// Without retries, concurrent changes only succeed if they are serialized.
+	client := testFirewallClient(t, 0)
+	require.NoError(t, aliases.PostRequest{Client: client, Name: "a", CIDR: "10.0.0.1"}.Post())
+
+	var wg sync.WaitGroup
+	errs := make([]error, 20)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = mutateFirewall(context.Background(), client, clusterFirewall, clusterFirewall+"/aliases", func(digest string) error {
+				cidr := fmt.Sprintf("10.0.1.%d", i)
+				return aliases.ItemPutRequest{Client: client, Name: "a", CIDR: cidr, Digest: &digest}.Put()
+			})
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+}
+
+func TestScopeLocksContext(t *testing.T) {
+	locks := &scopeLocks{locks: map[string]chan struct{}{}}
+	unlock, err := locks.lock(context.Background(), "a")
+	require.NoError(t, err)
+
+	// Other scopes are independent.
+	unlockB, err := locks.lock(context.Background(), "b")
+	require.NoError(t, err)
+	unlockB()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = locks.lock(ctx, "a")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	unlock()
+	unlock, err = locks.lock(context.Background(), "a")
+	require.NoError(t, err)
+	unlock()
+}
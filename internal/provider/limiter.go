@@ -0,0 +1,122 @@
+package provider
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	proxmox "github.com/c10l/proxmoxve-client-go/api"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// requestLimiter caps the number of API requests in flight and the rate at
+// which they are sent. A single limiter is shared by all the clients of the
+// provider, whatever their credentials.
+type requestLimiter struct {
+	// slots holds a value per request in flight, or is nil if unlimited.
+	slots chan struct{}
+
+	// interval is the minimum delay between the start of two requests, or 0
+	// if unlimited.
+	interval time.Duration
+
+	mu sync.Mutex
+	// next is when the next request may start.
+	next time.Time
+}
+
+// newRequestLimiter returns a limiter allowing maxConcurrent requests in
+// flight and perSecond requests per second. Zero means unlimited.
+func newRequestLimiter(maxConcurrent int, perSecond float64) *requestLimiter {
+	l := &requestLimiter{}
+	if maxConcurrent > 0 {
+		l.slots = make(chan struct{}, maxConcurrent)
+	}
+	if perSecond > 0 {
+		l.interval = time.Duration(float64(time.Second) / perSecond)
+	}
+	return l
+}
+
+// wait blocks until a request may be sent, returning early with the context
+// error if ctx is done first. release must be called once the request is
+// complete.
+func (l *requestLimiter) wait(ctx context.Context) (release func(), err error) {
+	release = func() {}
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		var once sync.Once
+		release = func() { once.Do(func() { <-l.slots }) }
+	}
+
+	if l.interval > 0 {
+		l.mu.Lock()
+		start := time.Now()
+		if l.next.After(start) {
+			start = l.next
+		}
+		l.next = start.Add(l.interval)
+		l.mu.Unlock()
+
+		if delay := time.Until(start); delay > 0 {
+			timer := time.NewTimer(delay)
+			defer timer.Stop()
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				release()
+				return nil, ctx.Err()
+			}
+		}
+	}
+	return release, nil
+}
+
+// limitTransport waits for the limiter before sending a request, and holds its
+// slot until the response body is closed.
+type limitTransport struct {
+	next    http.RoundTripper
+	limiter *requestLimiter
+}
+
+func (t *limitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	release, err := t.limiter.wait(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	if waited := time.Since(start); waited > time.Second {
+		tflog.Debug(req.Context(), "API request delayed by the request limits", map[string]interface{}{"waited": waited.String()})
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	resp.Body = &releaseOnClose{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// releaseOnClose calls release when the body is closed.
+type releaseOnClose struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releaseOnClose) Close() error {
+	defer b.release()
+	return b.ReadCloser.Close()
+}
+
+// withLimiter makes c wait for limiter before sending each request.
+func withLimiter(c *proxmox.Client, limiter *requestLimiter) *proxmox.Client {
+	c.HTTPClient.Transport = &limitTransport{next: transport(c.HTTPClient), limiter: limiter}
+	return c
+}
@@ -0,0 +1,105 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitTransportConcurrency(t *testing.T) {
+	var inFlight, maxInFlight int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}))
+	t.Cleanup(s.Close)
+
+	httpClient := &http.Client{Transport: &limitTransport{next: http.DefaultTransport, limiter: newRequestLimiter(2, 0)}}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := httpClient.Get(s.URL)
+			if assert.NoError(t, err) {
+				resp.Body.Close()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(2), maxInFlight)
+}
+
+func TestRequestLimiterRate(t *testing.T) {
+	l := newRequestLimiter(0, 50)
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		release, err := l.wait(context.Background())
+		require.NoError(t, err)
+		release()
+	}
+	// The first request starts immediately, the others 20ms apart.
+	assert.GreaterOrEqual(t, time.Since(start), 80*time.Millisecond)
+}
+
+func TestRequestLimiterContext(t *testing.T) {
+	l := newRequestLimiter(1, 0)
+	release, err := l.wait(context.Background())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = l.wait(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	release()
+	release, err = l.wait(context.Background())
+	require.NoError(t, err)
+	release()
+
+	// Waiting for the rate limit is cancelled too, and frees its slot.
+	l = newRequestLimiter(1, 1)
+	release, err = l.wait(context.Background())
+	require.NoError(t, err)
+	release()
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = l.wait(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Len(t, l.slots, 0)
+}
+
+func TestGetRequestLimiter(t *testing.T) {
+	t.Setenv("PROXMOXVE_MAX_CONCURRENT_REQUESTS", "4")
+	t.Setenv("PROXMOXVE_REQUESTS_PER_SECOND", "0.5")
+
+	var diags diag.Diagnostics
+	l := getRequestLimiter(ProxmoxVEProviderModel{MaxConcurrentRequests: types.Int64Null(), RequestsPerSecond: types.Float64Null()}, &diags)
+	require.False(t, diags.HasError())
+	assert.Equal(t, 4, cap(l.slots))
+	assert.Equal(t, 2*time.Second, l.interval)
+
+	l = getRequestLimiter(ProxmoxVEProviderModel{MaxConcurrentRequests: types.Int64Value(0), RequestsPerSecond: types.Float64Value(0)}, &diags)
+	require.False(t, diags.HasError())
+	assert.Nil(t, l.slots)
+	assert.Zero(t, l.interval)
+
+	getRequestLimiter(ProxmoxVEProviderModel{MaxConcurrentRequests: types.Int64Value(-1), RequestsPerSecond: types.Float64Null()}, &diags)
+	assert.True(t, diags.HasError())
+}
@@ -0,0 +1,157 @@
+package provider
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	proxmox "github.com/c10l/proxmoxve-client-go/api"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// cachedLists are the list endpoints whose responses are cached. Refreshing
+// the resources stored in them reads the whole list once instead of once per
+// resource.
+var cachedLists = map[string]bool{
+	"/storage":                  true,
+	"/cluster/firewall/aliases": true,
+	"/cluster/firewall/groups":  true,
+	"/cluster/firewall/ipset":   true,
+}
+
+// listCache holds the responses of the cachedLists until something is written
+// under them. The provider is configured anew for every Terraform operation,
+// so a refresh never sees the responses of a previous one.
+//
+// A single cache is shared by all the clients of the provider, so that a write
+// by one invalidates the responses cached by the others. The responses of each
+// client are cached separately, as their credentials may see different items.
+type listCache struct {
+	mu      sync.Mutex
+	entries map[string]*listCacheEntry
+}
+
+// listCacheEntry is a cached response, ready once the request filling it
+// completes.
+type listCacheEntry struct {
+	path  string
+	ready chan struct{}
+
+	// ok is set if the response can be reused.
+	ok     bool
+	status string
+	header http.Header
+	body   []byte
+}
+
+func newListCache() *listCache {
+	return &listCache{entries: map[string]*listCacheEntry{}}
+}
+
+// invalidate drops the responses of the lists path is stored under.
+func (c *listCache) invalidate(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, e := range c.entries {
+		if path == e.path || strings.HasPrefix(path, e.path+"/") {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// cacheTransport serves the GET requests of the cachedLists from cache, and
+// invalidates it on every other request. Concurrent requests for the same list
+// share a single response.
+type cacheTransport struct {
+	next  http.RoundTripper
+	cache *listCache
+
+	// scope keeps the entries of the client apart from those of the others.
+	scope string
+	// apiPath is the path of the API the paths of the cachedLists are
+	// relative to.
+	apiPath string
+}
+
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	path := strings.TrimPrefix(req.URL.Path, t.apiPath)
+	if req.Method != http.MethodGet {
+		resp, err := t.next.RoundTrip(req)
+		// Even a failed write may have changed something.
+		t.cache.invalidate(path)
+		return resp, err
+	}
+	if !cachedLists[path] {
+		return t.next.RoundTrip(req)
+	}
+
+	key := t.scope + " " + req.URL.String()
+	for {
+		t.cache.mu.Lock()
+		e, ok := t.cache.entries[key]
+		if !ok {
+			e = &listCacheEntry{path: path, ready: make(chan struct{})}
+			t.cache.entries[key] = e
+			t.cache.mu.Unlock()
+			return t.fill(req, key, e)
+		}
+		t.cache.mu.Unlock()
+
+		select {
+		case <-e.ready:
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+		if e.ok {
+			tflog.Trace(req.Context(), "Using cached API response", map[string]interface{}{"path": path})
+			return e.response(req), nil
+		}
+		// The request filling the entry failed, so send another one.
+	}
+}
+
+// fill sends req and caches its response in e if it succeeded.
+func (t *cacheTransport) fill(req *http.Request, key string, e *listCacheEntry) (*http.Response, error) {
+	defer close(e.ready)
+	resp, err := t.next.RoundTrip(req)
+	if err == nil && resp.StatusCode == http.StatusOK {
+		e.body, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err == nil {
+			e.ok, e.status, e.header = true, resp.Status, resp.Header
+			return e.response(req), nil
+		}
+		resp = nil
+	}
+
+	t.cache.mu.Lock()
+	if t.cache.entries[key] == e {
+		delete(t.cache.entries, key)
+	}
+	t.cache.mu.Unlock()
+	return resp, err
+}
+
+// response returns a copy of the cached response to req.
+func (e *listCacheEntry) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        e.status,
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(e.body)),
+		ContentLength: int64(len(e.body)),
+		Request:       req,
+	}
+}
+
+// withListCache makes c cache the responses of the cachedLists in cache, apart
+// from the other clients with another scope.
+func withListCache(c *proxmox.Client, cache *listCache, scope string) *proxmox.Client {
+	c.HTTPClient.Transport = &cacheTransport{next: transport(c.HTTPClient), cache: cache, scope: scope, apiPath: c.APIurl.Path}
+	return c
+}
@@ -0,0 +1,155 @@
+package provider
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+
+	"terraform-provider-proxmoxve/internal/pvemock"
+
+	proxmox "github.com/c10l/proxmoxve-client-go/api"
+	"github.com/c10l/proxmoxve-client-go/api/cluster/firewall/aliases"
+	"github.com/c10l/proxmoxve-client-go/api/cluster/firewall/ipset"
+	"github.com/c10l/proxmoxve-client-go/api/storage"
+	"github.com/c10l/proxmoxve-client-go/helpers"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingTransport counts the GET requests sent for each path.
+type countingTransport struct {
+	next http.RoundTripper
+
+	mu    sync.Mutex
+	count map[string]int
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method == http.MethodGet {
+		t.mu.Lock()
+		t.count[req.URL.Path]++
+		t.mu.Unlock()
+	}
+	return t.next.RoundTrip(req)
+}
+
+func (t *countingTransport) gets(path string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.count["/api2/json"+path]
+}
+
+// testCacheClients returns two clients of s sharing a cache with different
+// scopes, and the transport they send their requests through.
+func testCacheClients(t *testing.T, s *pvemock.Server) (*proxmox.Client, *proxmox.Client, *countingTransport) {
+	counter := &countingTransport{next: newHTTPTransport(&tls.Config{InsecureSkipVerify: true}, nil, nil), count: map[string]int{}}
+	cache := newListCache()
+	return withListCache(testTransportClient(t, s, counter), cache, "a"),
+		withListCache(testTransportClient(t, s, counter), cache, "b"),
+		counter
+}
+
+func TestListCache(t *testing.T) {
+	s := pvemock.New()
+	t.Cleanup(s.Close)
+	client, otherClient, counter := testCacheClients(t, s)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := ipset.GetRequest{Client: client}.Get()
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, counter.gets("/cluster/firewall/ipset"))
+
+	// Each client has its own entries.
+	_, err := ipset.GetRequest{Client: otherClient}.Get()
+	require.NoError(t, err)
+	assert.Equal(t, 2, counter.gets("/cluster/firewall/ipset"))
+
+	// Writing elsewhere leaves the list cached.
+	require.NoError(t, aliases.PostRequest{Client: client, Name: "a", CIDR: "10.0.0.1"}.Post())
+	_, err = ipset.GetRequest{Client: client}.Get()
+	require.NoError(t, err)
+	assert.Equal(t, 2, counter.gets("/cluster/firewall/ipset"))
+
+	// Writing under the list invalidates it for every client.
+	require.NoError(t, ipset.PostRequest{Client: otherClient, Name: "set"}.Post())
+	list, err := ipset.GetRequest{Client: client}.Get()
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+	assert.Equal(t, 3, counter.gets("/cluster/firewall/ipset"))
+
+	require.NoError(t, ipset.ItemDeleteRequest{Client: client, Name: "set"}.Delete())
+	list, err = ipset.GetRequest{Client: client}.Get()
+	require.NoError(t, err)
+	assert.Empty(t, list)
+
+	// Items are not cached.
+	for i := 0; i < 2; i++ {
+		_, err := aliases.ItemGetRequest{Client: client, Name: "a"}.Get()
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2, counter.gets("/cluster/firewall/aliases/a"))
+}
+
+func TestListCacheErrors(t *testing.T) {
+	s := pvemock.New()
+	t.Cleanup(s.Close)
+	client, _, counter := testCacheClients(t, s)
+	client.APIToken.Secret = "wrong"
+
+	for i := 0; i < 2; i++ {
+		_, err := storage.GetRequest{Client: client}.Get()
+		assert.ErrorContains(t, err, "401")
+	}
+	assert.Equal(t, 2, counter.gets("/storage"))
+}
+
+func TestReadStorage(t *testing.T) {
+	s := pvemock.New()
+	t.Cleanup(s.Close)
+	client, _, counter := testCacheClients(t, s)
+	for _, name := range []string{"a", "b"} {
+		_, err := storage.PostRequest{Client: client, Storage: name, StorageType: storage.TypeDir, DirPath: helpers.PtrTo("/" + name)}.Post()
+		require.NoError(t, err)
+	}
+
+	for _, name := range []string{"a", "b"} {
+		item, err := readStorage(context.Background(), client, name)
+		require.NoError(t, err)
+		assert.Equal(t, "/"+name, item.Path)
+	}
+	assert.Equal(t, 1, counter.gets("/storage"))
+
+	_, err := readStorage(context.Background(), client, "c")
+	assert.True(t, isNotFound(err, "c"), err)
+}
+
+func TestSharedClient(t *testing.T) {
+	calls := 0
+	getClient := sharedClient(func(context.Context, *diag.Diagnostics) (*proxmox.Client, error) {
+		calls++
+		if calls == 1 {
+			return nil, errors.New("401 authentication failure")
+		}
+		return &proxmox.Client{}, nil
+	})
+
+	_, err := getClient(context.Background(), &diag.Diagnostics{})
+	assert.Error(t, err)
+	first, err := getClient(context.Background(), &diag.Diagnostics{})
+	require.NoError(t, err)
+	second, err := getClient(context.Background(), &diag.Diagnostics{})
+	require.NoError(t, err)
+	assert.Same(t, first, second)
+	assert.Equal(t, 2, calls)
+}
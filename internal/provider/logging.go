@@ -0,0 +1,204 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	proxmox "github.com/c10l/proxmoxve-client-go/api"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// logSubsystem is the tflog subsystem API requests are logged to. Its level
+// follows TF_LOG_PROVIDER_PROXMOXVE, and can be set apart with
+// TF_LOG_PROVIDER_PROXMOXVE_API.
+const logSubsystem = "api"
+
+// logMask replaces the values that must not be logged.
+const logMask = "***"
+
+// sensitiveParams are the parameters and response fields whose values are
+// masked in the logs, wherever they are found.
+var sensitiveParams = map[string]bool{
+	"password":            true,
+	"secret":              true,
+	"root_password":       true,
+	"totp_seed":           true,
+	"ticket":              true,
+	"CSRFPreventionToken": true,
+	"tfa-challenge":       true,
+	"encryption-key":      true,
+}
+
+// sensitiveHeaders are the request headers whose values are masked in the
+// logs.
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Csrfpreventiontoken": true,
+}
+
+var authCookie = regexp.MustCompile(`(PVEAuthCookie=)[^;]*`)
+
+// isSensitiveParam reports whether the values of the parameter key of the API
+// endpoint path are masked in the logs.
+func isSensitiveParam(path, key string) bool {
+	if sensitiveParams[key] {
+		return true
+	}
+	// ACME plugin data holds the credentials of the DNS provider.
+	return key == "data" && strings.HasPrefix(path, "/cluster/acme/plugins")
+}
+
+// logTransport logs the requests it sends and their responses to the
+// logSubsystem: their method, path, status and latency at DEBUG, and their
+// headers, parameters and bodies at TRACE, with the sensitive values masked.
+type logTransport struct {
+	next http.RoundTripper
+
+	// apiPath is the path of the API the logged paths are relative to.
+	apiPath string
+}
+
+func (t *logTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := tflog.NewSubsystem(req.Context(), logSubsystem, tflog.WithLevelFromEnv("TF_LOG_PROVIDER_PROXMOXVE", logSubsystem))
+	path := strings.TrimPrefix(req.URL.Path, t.apiPath)
+	fields := map[string]interface{}{
+		"method": req.Method,
+		"path":   path,
+	}
+
+	tflog.SubsystemTrace(ctx, logSubsystem, "Sending Proxmox VE API request", map[string]interface{}{
+		"method":  req.Method,
+		"path":    path,
+		"query":   redactQuery(path, req.URL.Query()),
+		"headers": redactHeaders(req.Header),
+		"body":    requestBody(req, path),
+	})
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	fields["latency"] = time.Since(start).String()
+	if err != nil {
+		fields["error"] = err.Error()
+		tflog.SubsystemDebug(ctx, logSubsystem, "Proxmox VE API request failed", fields)
+		return nil, err
+	}
+	fields["status"] = resp.Status
+	tflog.SubsystemDebug(ctx, logSubsystem, "Received Proxmox VE API response", fields)
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	tflog.SubsystemTrace(ctx, logSubsystem, "Proxmox VE API response body", map[string]interface{}{
+		"method": req.Method,
+		"path":   path,
+		"body":   redactBody(path, body),
+	})
+	return resp, nil
+}
+
+// requestBody returns the body of req with its sensitive values masked,
+// leaving req able to send it.
+func requestBody(req *http.Request, path string) string {
+	if req.Body == nil || req.Body == http.NoBody || req.GetBody == nil {
+		return ""
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return ""
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return ""
+	}
+	if strings.HasPrefix(req.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		if form, err := url.ParseQuery(string(data)); err == nil {
+			return redactQuery(path, form)
+		}
+	}
+	return redactBody(path, data)
+}
+
+// redactQuery returns the encoded values with the sensitive ones masked.
+func redactQuery(path string, values url.Values) string {
+	for key := range values {
+		if isSensitiveParam(path, key) {
+			values[key] = []string{logMask}
+		}
+	}
+	return values.Encode()
+}
+
+// redactHeaders returns header with the credentials masked.
+func redactHeaders(header http.Header) map[string]string {
+	redacted := make(map[string]string, len(header))
+	for key, values := range header {
+		value := strings.Join(values, ", ")
+		switch {
+		case sensitiveHeaders[key]:
+			value = logMask
+		case key == "Cookie":
+			value = authCookie.ReplaceAllString(value, "${1}"+logMask)
+		}
+		redacted[key] = value
+	}
+	return redacted
+}
+
+// redactBody returns the JSON body of a response with the sensitive values
+// masked. Bodies which are not JSON are returned as is.
+func redactBody(path string, body []byte) string {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var envelope map[string]interface{}
+	if err := decoder.Decode(&envelope); err != nil {
+		return string(body)
+	}
+	// The response is wrapped in an object whose own data field is not
+	// sensitive.
+	for key, value := range envelope {
+		if key != "data" && isSensitiveParam(path, key) {
+			envelope[key] = logMask
+			continue
+		}
+		envelope[key] = redactValue(path, value)
+	}
+	redacted, err := json.Marshal(envelope)
+	if err != nil {
+		return string(body)
+	}
+	return string(redacted)
+}
+
+func redactValue(path string, value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, v := range value {
+			if isSensitiveParam(path, key) {
+				value[key] = logMask
+			} else {
+				value[key] = redactValue(path, v)
+			}
+		}
+	case []interface{}:
+		for i, v := range value {
+			value[i] = redactValue(path, v)
+		}
+	}
+	return value
+}
+
+// withLogging makes c log the requests it sends and their responses.
+func withLogging(c *proxmox.Client) *proxmox.Client {
+	c.HTTPClient.Transport = &logTransport{next: transport(c.HTTPClient), apiPath: c.APIurl.Path}
+	return c
+}
@@ -0,0 +1,103 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-log/tflogtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testLogRequests sends a request to an ACME plugin and a login request
+// through a logTransport, and returns the entries logged.
+func testLogRequests(t *testing.T) []map[string]interface{} {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api2/json/cluster/acme/plugins":
+			_, _ = w.Write([]byte(`{"data":{"plugin":"example","data":"CF_Token=plugin-secret"}}`))
+		case "/api2/json/access/ticket":
+			_, _ = w.Write([]byte(`{"data":{"username":"root@pam","ticket":"PVE:root@pam:ticket-secret","CSRFPreventionToken":"csrf-secret"}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(s.Close)
+
+	var output bytes.Buffer
+	ctx := tflogtest.RootLogger(context.Background(), &output)
+	httpClient := &http.Client{Transport: &logTransport{next: http.DefaultTransport, apiPath: "/api2/json"}}
+
+	query := url.Values{"id": {"example"}, "data": {"CF_Token=plugin-secret"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL+"/api2/json/cluster/acme/plugins?"+query.Encode(), nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "PVEAPIToken=terraform@pve!provider=token-secret")
+	resp, err := httpClient.Do(req)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	// The response is still read in full by the client.
+	assert.Contains(t, string(body), "plugin-secret")
+
+	form := url.Values{"username": {"root@pam"}, "password": {"password-secret"}, "totp_seed": {"totp-secret"}}
+	req, err = http.NewRequestWithContext(ctx, http.MethodPost, s.URL+"/api2/json/access/ticket", strings.NewReader(form.Encode()))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Cookie", "PVEAuthCookie=cookie-secret")
+	req.Header.Set("CSRFPreventionToken", "csrf-secret")
+	resp, err = httpClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.NotContains(t, output.String(), "-secret")
+	entries, err := tflogtest.MultilineJSONDecode(&output)
+	require.NoError(t, err)
+	return entries
+}
+
+func TestLogTransport(t *testing.T) {
+	entries := testLogRequests(t)
+	require.Len(t, entries, 6)
+
+	request, response, body := entries[0], entries[1], entries[2]
+	assert.Equal(t, "trace", request["@level"])
+	assert.Equal(t, "provider.api", request["@module"])
+	assert.Equal(t, "POST", request["method"])
+	assert.Equal(t, "/cluster/acme/plugins", request["path"])
+	assert.Equal(t, "data=%2A%2A%2A&id=example", request["query"])
+	assert.Equal(t, "***", request["headers"].(map[string]interface{})["Authorization"])
+
+	assert.Equal(t, "debug", response["@level"])
+	assert.Equal(t, "200 OK", response["status"])
+	assert.NotEmpty(t, response["latency"])
+
+	assert.Equal(t, "trace", body["@level"])
+	assert.JSONEq(t, `{"data":{"plugin":"example","data":"***"}}`, body["body"].(string))
+
+	request, body = entries[3], entries[5]
+	assert.Equal(t, "/access/ticket", request["path"])
+	assert.Equal(t, "password=%2A%2A%2A&totp_seed=%2A%2A%2A&username=root%40pam", request["body"])
+	headers := request["headers"].(map[string]interface{})
+	assert.Equal(t, "PVEAuthCookie=***", headers["Cookie"])
+	assert.Equal(t, "***", headers["Csrfpreventiontoken"])
+	assert.JSONEq(t, `{"data":{"username":"root@pam","ticket":"***","CSRFPreventionToken":"***"}}`, body["body"].(string))
+}
+
+func TestLogTransportLevel(t *testing.T) {
+	t.Setenv("TF_LOG_PROVIDER_PROXMOXVE_API", "DEBUG")
+	entries := testLogRequests(t)
+	require.Len(t, entries, 2)
+	for _, entry := range entries {
+		assert.Equal(t, "debug", entry["@level"])
+	}
+
+	t.Setenv("TF_LOG_PROVIDER_PROXMOXVE_API", "OFF")
+	assert.Empty(t, testLogRequests(t))
+}
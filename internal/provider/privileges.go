@@ -0,0 +1,222 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	proxmox "github.com/c10l/proxmoxve-client-go/api"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// privilege is a privilege needed on an ACL path, e.g. `Sys.Modify` on `/`.
+type privilege struct {
+	Path string
+	Name string
+}
+
+func (p privilege) String() string {
+	return p.Name + " on " + p.Path
+}
+
+// privilegesFunc returns the privileges needed to apply a change to the
+// resource called name: to create it if create is set, to update or delete it
+// otherwise. name is empty if not known yet.
+type privilegesFunc func(name string, create bool) []privilege
+
+// privilegeRoles are the built-in roles suggested to grant each privilege: the
+// least privileged one including it.
+var privilegeRoles = map[string]string{
+	"Datastore.Allocate":      "PVEDatastoreAdmin",
+	"Datastore.AllocateSpace": "PVEDatastoreUser",
+	"Datastore.Audit":         "PVEAuditor",
+	"Sys.Audit":               "PVEAuditor",
+	"Sys.Modify":              "Administrator",
+}
+
+// permissions are the effective privileges of a credential by ACL path, as
+// returned by /access/permissions. The value of each privilege is 1 if it
+// propagates to the paths below.
+type permissions map[string]map[string]int
+
+// empty reports whether p grants no privilege at all.
+func (p permissions) empty() bool {
+	for _, privs := range p {
+		if len(privs) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// has reports whether p grants priv on aclPath, either on the path itself or
+// propagated from the closest path above it with privileges of its own.
+func (p permissions) has(aclPath, priv string) bool {
+	for current := aclPath; ; {
+		if privs, ok := p[current]; ok {
+			propagate, granted := privs[priv]
+			return granted && (current == aclPath || propagate == 1)
+		}
+		if current == "/" {
+			return false
+		}
+		current = current[:strings.LastIndex(current, "/")]
+		if current == "" {
+			current = "/"
+		}
+	}
+}
+
+// missing returns the privileges of required which p does not grant.
+func (p permissions) missing(required []privilege) []privilege {
+	var missing []privilege
+	for _, r := range required {
+		if !p.has(r.Path, r.Name) {
+			missing = append(missing, r)
+		}
+	}
+	return missing
+}
+
+// permissionsCache holds the permissions of each client of the provider, read
+// once per run. A nil permissionsCache reads them on every call.
+type permissionsCache struct {
+	mu       sync.Mutex
+	byClient map[*proxmox.Client]permissions
+}
+
+// get returns the permissions of client.
+func (c *permissionsCache) get(ctx context.Context, client *proxmox.Client) (permissions, error) {
+	if c == nil {
+		return readPermissions(ctx, client)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if perms, ok := c.byClient[client]; ok {
+		return perms, nil
+	}
+	perms, err := readPermissions(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	if c.byClient == nil {
+		c.byClient = map[*proxmox.Client]permissions{}
+	}
+	c.byClient[client] = perms
+	return perms, nil
+}
+
+// readPermissions reads the permissions of the credential of client.
+func readPermissions(ctx context.Context, client *proxmox.Client) (permissions, error) {
+	apiURL := client.APIurl
+	apiURL.Path += "/access/permissions"
+	body, err := withContext(ctx, client).Get(&apiURL)
+	if err != nil {
+		return nil, err
+	}
+	var perms permissions
+	if err := json.Unmarshal(body, &perms); err != nil {
+		return nil, err
+	}
+	return perms, nil
+}
+
+// checkPrivileges reports at plan time the privileges the credential of the
+// resource typeName lacks to apply the change planned by req, instead of
+// failing midway through the apply. It is called by the ModifyPlan method of
+// the resources.
+//
+// Nothing is reported if the permissions cannot be read: the apply reports
+// whatever goes wrong then.
+func (d *providerData) checkPrivileges(ctx context.Context, typeName string, defaultMethod authMethod, privileges privilegesFunc, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if d == nil || req.Plan.Raw.Equal(req.State.Raw) {
+		return
+	}
+	values := req.Plan
+	if req.Plan.Raw.IsNull() {
+		values = tfsdk.Plan(req.State)
+	}
+	var auth, name types.String
+	resp.Diagnostics.Append(values.GetAttribute(ctx, path.Root("auth"), &auth)...)
+	if _, ok := values.Schema.GetAttributes()["name"]; ok {
+		resp.Diagnostics.Append(values.GetAttribute(ctx, path.Root("name"), &name)...)
+	}
+	if resp.Diagnostics.HasError() || auth.IsUnknown() {
+		return
+	}
+
+	var clientDiags diag.Diagnostics
+	client := d.client(ctx, typeName, auth, defaultMethod, &clientDiags)
+	if clientDiags.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(clientDiags...)
+	perms, err := d.permissions.get(ctx, client)
+	if err != nil {
+		tflog.Warn(ctx, "Unable to read the permissions of the Proxmox VE credential", map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	missing := perms.missing(privileges(name.ValueString(), req.State.Raw.IsNull()))
+	if len(missing) == 0 {
+		return
+	}
+	resp.Diagnostics.AddError("Missing privileges for "+typeName, describeMissingPrivileges(client, missing))
+}
+
+// describeMissingPrivileges lists the privileges missing from the credential
+// of client, and how to grant them.
+func describeMissingPrivileges(client *proxmox.Client, missing []privilege) string {
+	grantee := "--users <user>"
+	if client.APIToken != nil {
+		grantee = "--tokens " + client.APIToken.TokenID
+	}
+
+	var lines []string
+	rolesByPath := map[string]map[string]bool{}
+	for _, p := range missing {
+		lines = append(lines, "- "+p.String())
+		if rolesByPath[p.Path] == nil {
+			rolesByPath[p.Path] = map[string]bool{}
+		}
+		rolesByPath[p.Path][privilegeRoles[p.Name]] = true
+	}
+	var commands []string
+	for aclPath, roles := range rolesByPath {
+		var names []string
+		for role := range roles {
+			if role != "" {
+				names = append(names, role)
+			}
+		}
+		if len(names) == 0 {
+			continue
+		}
+		sort.Strings(names)
+		commands = append(commands, fmt.Sprintf("    pveum acl modify %s --roles %s %s", aclPath, strings.Join(names, ","), grantee))
+	}
+	sort.Strings(commands)
+
+	msg := "The credential lacks the following privileges:\n\n" + strings.Join(lines, "\n")
+	if len(commands) > 0 {
+		msg += "\n\nThey can be granted with a role including them, e.g.:\n\n" + strings.Join(commands, "\n")
+	}
+	if client.APIToken != nil {
+		msg += "\n\nA privilege-separated API token needs the privileges granted to both the token and its user."
+	}
+	return msg
+}
+
+// sysModifyPrivileges are the privileges needed to manage the configuration of
+// the cluster, e.g. its firewall or ACME plugins.
+func sysModifyPrivileges(name string, create bool) []privilege {
+	return []privilege{{Path: "/", Name: "Sys.Modify"}}
+}
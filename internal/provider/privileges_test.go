@@ -0,0 +1,146 @@
+package provider
+
+import (
+	"context"
+	"crypto/tls"
+	"testing"
+
+	"terraform-provider-proxmoxve/internal/pvemock"
+
+	proxmox "github.com/c10l/proxmoxve-client-go/api"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPermissionsHas(t *testing.T) {
+	perms := permissions{
+		"/":                {"Sys.Audit": 1, "Sys.Modify": 0},
+		"/storage":         {"Datastore.Audit": 1, "Datastore.Allocate": 1},
+		"/storage/local":   {"Datastore.Audit": 1},
+		"/vms/100":         {"VM.Audit": 0},
+		"/storage/backups": {"Datastore.AllocateSpace": 0},
+	}
+
+	cases := []struct {
+		path, priv string
+		expected   bool
+	}{
+		{"/", "Sys.Modify", true},
+		{"/", "Datastore.Allocate", false},
+		{"/nodes/pve", "Sys.Audit", true},
+		{"/nodes/pve", "Sys.Modify", false},
+		{"/storage", "Datastore.Allocate", true},
+		{"/storage/nfs", "Datastore.Allocate", true},
+		// The privileges of the closest path listed replace those above.
+		{"/storage/local", "Datastore.Allocate", false},
+		{"/storage/local", "Datastore.Audit", true},
+		{"/storage/backups/dump", "Datastore.AllocateSpace", false},
+		{"/vms/100", "VM.Audit", true},
+	}
+	for _, tc := range cases {
+		assert.Equal(t, tc.expected, perms.has(tc.path, tc.priv), "%s on %s", tc.priv, tc.path)
+	}
+
+	assert.Equal(t,
+		[]privilege{{Path: "/storage/local", Name: "Datastore.Allocate"}, {Path: "/", Name: "Datastore.Audit"}},
+		perms.missing([]privilege{
+			{Path: "/storage/local", Name: "Datastore.Allocate"},
+			{Path: "/storage/nfs", Name: "Datastore.Allocate"},
+			{Path: "/", Name: "Datastore.Audit"},
+		}),
+	)
+}
+
+func TestPermissionsCache(t *testing.T) {
+	s := pvemock.New()
+	t.Cleanup(s.Close)
+	client := testTransportClient(t, s, newHTTPTransport(&tls.Config{InsecureSkipVerify: true}, nil, nil))
+
+	s.SetPermissions(map[string]map[string]int{"/storage": {"Datastore.Allocate": 1}})
+	cache := &permissionsCache{}
+	perms, err := cache.get(context.Background(), client)
+	require.NoError(t, err)
+	assert.True(t, perms.has("/storage/local", "Datastore.Allocate"))
+
+	// The permissions are read once per client.
+	s.SetPermissions(map[string]map[string]int{})
+	perms, err = cache.get(context.Background(), client)
+	require.NoError(t, err)
+	assert.True(t, perms.has("/storage/local", "Datastore.Allocate"))
+
+	var uncached *permissionsCache
+	perms, err = uncached.get(context.Background(), client)
+	require.NoError(t, err)
+	assert.False(t, perms.has("/storage/local", "Datastore.Allocate"))
+}
+
+// testModifyPlanRequest returns the request of ModifyPlan changing a resource
+// from state to plan, either of which may be nil. The configuration is the
+// plan.
+func testModifyPlanRequest(state, plan map[string]string) resource.ModifyPlanRequest {
+	s := schema.Schema{Attributes: map[string]schema.Attribute{
+		"name": schema.StringAttribute{Required: true},
+		"path": schema.StringAttribute{Required: true},
+		"auth": schema.StringAttribute{Optional: true},
+	}}
+	objectType := tftypes.Object{AttributeTypes: map[string]tftypes.Type{"name": tftypes.String, "path": tftypes.String, "auth": tftypes.String}}
+	value := func(attrs map[string]string) tftypes.Value {
+		if attrs == nil {
+			return tftypes.NewValue(objectType, nil)
+		}
+		return tftypes.NewValue(objectType, map[string]tftypes.Value{
+			"name": tftypes.NewValue(tftypes.String, attrs["name"]),
+			"path": tftypes.NewValue(tftypes.String, attrs["path"]),
+			"auth": tftypes.NewValue(tftypes.String, nil),
+		})
+	}
+	return resource.ModifyPlanRequest{
+		Config: tfsdk.Config{Schema: s, Raw: value(plan)},
+		State:  tfsdk.State{Schema: s, Raw: value(state)},
+		Plan:   tfsdk.Plan{Schema: s, Raw: value(plan)},
+	}
+}
+
+func TestCheckPrivileges(t *testing.T) {
+	s := pvemock.New()
+	t.Cleanup(s.Close)
+	client := testTransportClient(t, s, newHTTPTransport(&tls.Config{InsecureSkipVerify: true}, nil, nil))
+	d := &providerData{
+		tokenClient:  func(context.Context, *diag.Diagnostics) (*proxmox.Client, error) { return client, nil },
+		ticketClient: func(context.Context, *diag.Diagnostics) (*proxmox.Client, error) { return client, nil },
+	}
+	s.SetPermissions(map[string]map[string]int{
+		"/":              {"Sys.Audit": 1},
+		"/storage/local": {"Datastore.Allocate": 0},
+	})
+
+	check := func(req resource.ModifyPlanRequest) *resource.ModifyPlanResponse {
+		resp := &resource.ModifyPlanResponse{}
+		d.checkPrivileges(context.Background(), "storage_dir", authToken, storagePrivileges, req, resp)
+		return resp
+	}
+
+	resp := check(testModifyPlanRequest(nil, map[string]string{"name": "nfs"}))
+	require.True(t, resp.Diagnostics.HasError())
+	assert.Equal(t, "Missing privileges for storage_dir", resp.Diagnostics[0].Summary())
+	assert.Contains(t, resp.Diagnostics[0].Detail(), "- Datastore.Allocate on /storage\n")
+	assert.Contains(t, resp.Diagnostics[0].Detail(), "pveum acl modify /storage --roles PVEDatastoreAdmin --tokens "+s.TokenID)
+
+	local := map[string]string{"name": "local", "path": "/var/lib/vz"}
+	assert.False(t, check(testModifyPlanRequest(local, map[string]string{"name": "local", "path": "/srv"})).Diagnostics.HasError())
+	assert.False(t, check(testModifyPlanRequest(local, nil)).Diagnostics.HasError())
+	resp = check(testModifyPlanRequest(map[string]string{"name": "nfs"}, nil))
+	require.True(t, resp.Diagnostics.HasError())
+	assert.Contains(t, resp.Diagnostics[0].Detail(), "- Datastore.Allocate on /storage/nfs")
+
+	// Nothing is checked without changes, or when the permissions cannot be read.
+	s.SetPermissions(map[string]map[string]int{})
+	assert.False(t, check(testModifyPlanRequest(map[string]string{"name": "nfs"}, map[string]string{"name": "nfs"})).Diagnostics.HasError())
+	client.APIToken.Secret = "revoked"
+	assert.False(t, check(testModifyPlanRequest(nil, map[string]string{"name": "nfs"})).Diagnostics.HasError())
+}
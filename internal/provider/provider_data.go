@@ -0,0 +1,180 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	proxmox "github.com/c10l/proxmoxve-client-go/api"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// authMethod is a kind of credential the provider authenticates with.
+type authMethod string
+
+const (
+	// authToken authenticates with the API token set by token_id and secret.
+	authToken authMethod = "token"
+	// authTicket authenticates with a ticket acquired by username and password.
+	authTicket authMethod = "ticket"
+)
+
+var authMethods = []authMethod{authToken, authTicket}
+
+// description names the attributes which configure the credential.
+func (m authMethod) description() string {
+	if m == authTicket {
+		return "a ticket (`username` and `password`, or `root_password`)"
+	}
+	return "an API token (`token_id` and `secret`)"
+}
+
+// providerData is passed by the provider to its resources and data sources.
+type providerData struct {
+	// authPreference is the credential set by the provider attribute
+	// auth_preference, or empty if unset.
+	authPreference authMethod
+
+	tokenClient  getClientFunc
+	ticketClient getClientFunc
+
+	// release is the release of Proxmox VE the provider connected to, in `x.y`
+	// format, or empty if not known.
+	release string
+
+	// permissions caches the permissions checked by checkPrivileges.
+	permissions *permissionsCache
+}
+
+// newClient returns a client authenticated with method.
+func (d *providerData) newClient(ctx context.Context, method authMethod, diags *diag.Diagnostics) (*proxmox.Client, error) {
+	if method == authTicket {
+		return d.ticketClient(ctx, diags)
+	}
+	return d.tokenClient(ctx, diags)
+}
+
+// sharedClient returns a getClientFunc creating a client with newClient on
+// first use, and the same client afterwards. All resources and data sources
+// thus share the connections, ticket and cached responses of a client. It
+// tries again on every call until newClient succeeds.
+func sharedClient(newClient getClientFunc) getClientFunc {
+	var mu sync.Mutex
+	var client *proxmox.Client
+	return func(ctx context.Context, diags *diag.Diagnostics) (*proxmox.Client, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if client == nil {
+			c, err := newClient(ctx, diags)
+			if err != nil {
+				return nil, err
+			}
+			client = c
+		}
+		return client, nil
+	}
+}
+
+// errMissingCredentials is wrapped by the errors of getClientFunc when the
+// credential it needs is not configured.
+var errMissingCredentials = errors.New("missing credentials")
+
+// client returns a client authenticated with the credential chosen for the
+// resource typeName: the one set by its `auth` attribute, else the provider's
+// auth_preference, else defaultMethod. Unless set by `auth`, the other
+// credential is used when the chosen one is not configured.
+func (d *providerData) client(ctx context.Context, typeName string, auth types.String, defaultMethod authMethod, diags *diag.Diagnostics) *proxmox.Client {
+	if !auth.IsNull() && !auth.IsUnknown() {
+		method := authMethod(auth.ValueString())
+		client, err := d.newClient(ctx, method, diags)
+		if err != nil {
+			diags.AddAttributeError(
+				path.Root("auth"),
+				"Unable to authenticate "+typeName,
+				fmt.Sprintf("%s is set to authenticate with %s:\n\n%s", typeName, method.description(), err),
+			)
+		}
+		return client
+	}
+
+	method := defaultMethod
+	if d.authPreference != "" {
+		method = d.authPreference
+	}
+	client, err := d.newClient(ctx, method, diags)
+	if errors.Is(err, errMissingCredentials) {
+		other := authToken
+		if method == authToken {
+			other = authTicket
+		}
+		var otherErr error
+		client, otherErr = d.newClient(ctx, other, diags)
+		if errors.Is(otherErr, errMissingCredentials) {
+			diags.AddError(
+				"Missing credentials for "+typeName,
+				fmt.Sprintf("%s needs either %s or %s, but neither is configured:\n\n- %s\n- %s", typeName, method.description(), other.description(), err, otherErr),
+			)
+			return nil
+		}
+		err = otherErr
+	}
+	if err != nil {
+		diags.AddError("Unable to authenticate "+typeName, err.Error())
+	}
+	return client
+}
+
+// configureProviderData returns the providerData passed to Configure, or nil
+// if the provider is not configured yet.
+func configureProviderData(providerDataAny any, diags *diag.Diagnostics) *providerData {
+	if providerDataAny == nil {
+		return nil
+	}
+	data, ok := providerDataAny.(*providerData)
+	if !ok {
+		diags.AddError(
+			"Unexpected Configure Type",
+			fmt.Sprintf("Expected *providerData, got: %T. Please report this issue to the provider developers.", providerDataAny),
+		)
+		return nil
+	}
+	return data
+}
+
+// authAttribute is the `auth` attribute accepted by every resource, which
+// authenticates with defaultMethod unless the provider sets auth_preference.
+func authAttribute(defaultMethod authMethod) schema.StringAttribute {
+	return schema.StringAttribute{
+		Optional:            true,
+		Validators:          []validator.String{authMethodValidator{}},
+		MarkdownDescription: fmt.Sprintf("Credential used to manage this resource: `token` or `ticket`. Defaults to the provider's `auth_preference`, or `%s`.", defaultMethod),
+	}
+}
+
+// authMethodValidator checks that a string is an authMethod.
+type authMethodValidator struct{}
+
+func (v authMethodValidator) Description(ctx context.Context) string {
+	return "value must be one of `token` or `ticket`"
+}
+
+func (v authMethodValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v authMethodValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+	for _, m := range authMethods {
+		if req.ConfigValue.ValueString() == string(m) {
+			return
+		}
+	}
+	resp.Diagnostics.AddAttributeError(req.Path, "Invalid authentication method", fmt.Sprintf("%q is not valid: %s", req.ConfigValue.ValueString(), v.Description(ctx)))
+}
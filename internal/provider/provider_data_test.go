@@ -0,0 +1,57 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	proxmox "github.com/c10l/proxmoxve-client-go/api"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProviderDataClient(t *testing.T) {
+	tokenClient, ticketClient := &proxmox.Client{}, &proxmox.Client{}
+	configured := func(c *proxmox.Client) getClientFunc {
+		return func(context.Context, *diag.Diagnostics) (*proxmox.Client, error) { return c, nil }
+	}
+	missing := func(name string) getClientFunc {
+		return func(context.Context, *diag.Diagnostics) (*proxmox.Client, error) {
+			return nil, fmt.Errorf("%w: %s cannot be empty", errMissingCredentials, name)
+		}
+	}
+	failing := func(context.Context, *diag.Diagnostics) (*proxmox.Client, error) {
+		return nil, errors.New("401 authentication failure")
+	}
+
+	for _, tc := range []struct {
+		name       string
+		data       providerData
+		auth       types.String
+		defaultM   authMethod
+		client     *proxmox.Client
+		errSummary string
+	}{
+		{"default", providerData{tokenClient: configured(tokenClient), ticketClient: configured(ticketClient)}, types.StringNull(), authTicket, ticketClient, ""},
+		{"preference", providerData{authPreference: authToken, tokenClient: configured(tokenClient), ticketClient: configured(ticketClient)}, types.StringNull(), authTicket, tokenClient, ""},
+		{"override", providerData{authPreference: authToken, tokenClient: configured(tokenClient), ticketClient: configured(ticketClient)}, types.StringValue("ticket"), authToken, ticketClient, ""},
+		{"fallback", providerData{tokenClient: configured(tokenClient), ticketClient: missing("password")}, types.StringNull(), authTicket, tokenClient, ""},
+		{"no fallback on failure", providerData{tokenClient: configured(tokenClient), ticketClient: failing}, types.StringNull(), authTicket, nil, "Unable to authenticate acme_account"},
+		{"no fallback on override", providerData{tokenClient: configured(tokenClient), ticketClient: missing("password")}, types.StringValue("ticket"), authToken, nil, "Unable to authenticate acme_account"},
+		{"nothing configured", providerData{tokenClient: missing("token_id"), ticketClient: missing("password")}, types.StringNull(), authTicket, nil, "Missing credentials for acme_account"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var diags diag.Diagnostics
+			client := tc.data.client(context.Background(), "acme_account", tc.auth, tc.defaultM, &diags)
+			if tc.errSummary != "" {
+				assert.True(t, diags.HasError())
+				assert.Equal(t, tc.errSummary, diags.Errors()[0].Summary())
+				return
+			}
+			assert.False(t, diags.HasError(), diags)
+			assert.Same(t, tc.client, client)
+		})
+	}
+}
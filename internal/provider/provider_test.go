@@ -1,10 +1,27 @@
 package provider
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"testing"
 
+	"terraform-provider-proxmoxve/internal/pvemock"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/stretchr/testify/assert"
 )
 
 // testAccProtoV6ProviderFactories are used to instantiate a provider during
@@ -15,8 +32,216 @@ var testAccProtoV6ProviderFactories = map[string]func() (tfprotov6.ProviderServe
 	"proxmoxve": providerserver.NewProtocol6WithError(New("test")()),
 }
 
+var (
+	testAccMockServer     *pvemock.Server
+	testAccMockServerOnce sync.Once
+)
+
+// testAccPreCheck points the provider at the in-memory Proxmox VE API server
+// when no real server was configured via PROXMOXVE_BASE_URL.
 func testAccPreCheck(t *testing.T) {
-	// You can add code here to run prior to any test case execution, for example assertions
-	// about the appropriate environment variables being set are common to see in a pre-check
-	// function.
+	if os.Getenv("PROXMOXVE_BASE_URL") != "" {
+		return
+	}
+
+	testAccMockServerOnce.Do(func() {
+		testAccMockServer = pvemock.New()
+	})
+	t.Setenv("PROXMOXVE_BASE_URL", testAccMockServer.URL)
+	t.Setenv("PROXMOXVE_TOKEN_ID", testAccMockServer.TokenID)
+	t.Setenv("PROXMOXVE_SECRET", testAccMockServer.Secret)
+	t.Setenv("PROXMOXVE_ROOT_PASSWORD", testAccMockServer.RootPassword)
+	t.Setenv("PROXMOXVE_TLS_INSECURE", "true")
+}
+
+func TestAccProviderBaseURLs(t *testing.T) {
+	// The configurations below need PROXMOXVE_BASE_URL.
+	testAccPreCheck(t)
+	baseURL := os.Getenv("PROXMOXVE_BASE_URL")
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			// The first node is down, so the provider fails over to the second one.
+			{
+				Config: fmt.Sprintf(`
+					provider "proxmoxve" {
+						base_urls = [%q, %q]
+					}
+
+					data "proxmoxve_version" "test" {}
+				`, testDownEndpoint(), baseURL),
+				Check: resource.TestCheckResourceAttrWith("data.proxmoxve_version.test", "release", testAccRegexpMatch(`7\.\d+`)),
+			},
+		},
+	})
+}
+
+func TestAccProviderCACert(t *testing.T) {
+	testAccPreCheck(t)
+	if testAccMockServer == nil {
+		t.Skip("the certificate of a real server is not known")
+	}
+	cert := testAccMockServer.Certificate()
+	sum := sha256.Sum256(cert.Raw)
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+					provider "proxmoxve" {
+						tls_insecure = false
+						ca_cert_pem  = %q
+					}
+
+					data "proxmoxve_version" "test" {}
+				`, caPEM),
+				Check: resource.TestCheckResourceAttrWith("data.proxmoxve_version.test", "release", testAccRegexpMatch(`7\.\d+`)),
+			},
+			{
+				Config: fmt.Sprintf(`
+					provider "proxmoxve" {
+						tls_insecure           = false
+						tls_fingerprint_sha256 = %q
+					}
+
+					data "proxmoxve_version" "test" {}
+				`, formatFingerprint(sum[:])),
+				Check: resource.TestCheckResourceAttrWith("data.proxmoxve_version.test", "release", testAccRegexpMatch(`7\.\d+`)),
+			},
+		},
+	})
+}
+
+func TestAccProviderProxy(t *testing.T) {
+	testAccPreCheck(t)
+	proxy, connections := testHTTPProxy(t)
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+					provider "proxmoxve" {
+						proxy_url = %q
+					}
+
+					data "proxmoxve_version" "test" {}
+				`, proxy.URL),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrWith("data.proxmoxve_version.test", "release", testAccRegexpMatch(`7\.\d+`)),
+					func(*terraform.State) error {
+						if atomic.LoadInt32(connections) == 0 {
+							return errors.New("no connection went through the proxy")
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
+
+func TestAccProviderTLSInsecureUnset(t *testing.T) {
+	testAccPreCheck(t)
+	if testAccMockServer == nil {
+		t.Skip("the certificate of a real server is not known")
+	}
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: testAccMockServer.Certificate().Raw})
+
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			t.Setenv("PROXMOXVE_TLS_INSECURE", "")
+		},
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+					provider "proxmoxve" {
+						ca_cert_pem = %q
+					}
+
+					data "proxmoxve_version" "test" {}
+				`, caPEM),
+				Check: resource.TestCheckResourceAttrWith("data.proxmoxve_version.test", "release", testAccRegexpMatch(`7\.\d+`)),
+			},
+		},
+	})
+}
+
+func TestAccProviderCredentialProcess(t *testing.T) {
+	testAccPreCheck(t)
+	creds := filepath.Join(t.TempDir(), "credentials.json")
+	err := os.WriteFile(creds, []byte(fmt.Sprintf(`{"token_id": %q, "secret": %q}`, os.Getenv("PROXMOXVE_TOKEN_ID"), os.Getenv("PROXMOXVE_SECRET"))), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			t.Setenv("PROXMOXVE_TOKEN_ID", "")
+			t.Setenv("PROXMOXVE_SECRET", "")
+		},
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+					provider "proxmoxve" {
+						credential_process = "cat %s"
+					}
+
+					data "proxmoxve_version" "test" {}
+				`, creds),
+				Check: resource.TestCheckResourceAttrWith("data.proxmoxve_version.test", "release", testAccRegexpMatch(`7\.\d+`)),
+			},
+		},
+	})
+}
+
+func TestAccProviderRequestLimits(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+					provider "proxmoxve" {
+						max_concurrent_requests = 1
+						requests_per_second     = 20
+					}
+
+					data "proxmoxve_version" "test" {}
+					data "proxmoxve_firewall_refs" "test" {}
+				`,
+				Check: resource.TestCheckResourceAttrWith("data.proxmoxve_version.test", "release", testAccRegexpMatch(`7\.\d+`)),
+			},
+		},
+	})
+}
+
+func TestGetBaseURLs(t *testing.T) {
+	t.Setenv("PROXMOXVE_BASE_URL", "https://pve1:8006")
+	t.Setenv("PROXMOXVE_BASE_URLS", "")
+
+	var diags diag.Diagnostics
+	data := ProxmoxVEProviderModel{BaseURL: types.StringNull(), BaseURLs: types.ListNull(types.StringType)}
+	assert.Equal(t, []string{"https://pve1:8006"}, getBaseURLs(context.Background(), data, &diags))
+
+	t.Setenv("PROXMOXVE_BASE_URLS", "https://pve1:8006, https://pve2:8006,")
+	assert.Equal(t, []string{"https://pve1:8006", "https://pve2:8006"}, getBaseURLs(context.Background(), data, &diags))
+
+	data.BaseURLs = types.ListValueMust(types.StringType, []attr.Value{types.StringValue("https://pve3:8006")})
+	assert.Equal(t, []string{"https://pve3:8006"}, getBaseURLs(context.Background(), data, &diags))
+	assert.False(t, diags.HasError())
+
+	data.BaseURL = types.StringValue("https://pve1:8006")
+	getBaseURLs(context.Background(), data, &diags)
+	assert.True(t, diags.HasError())
 }
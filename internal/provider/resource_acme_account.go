@@ -2,14 +2,14 @@ package provider
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	"os"
 	"strings"
-	"time"
 
 	proxmox "github.com/c10l/proxmoxve-client-go/api"
 	"github.com/c10l/proxmoxve-client-go/api/cluster/acme/account"
 	"github.com/c10l/proxmoxve-client-go/helpers"
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
@@ -22,6 +22,7 @@ import (
 // Ensure provider defined types fully satisfy framework interfaces
 var _ resource.Resource = &ACMEAccountResource{}
 var _ resource.ResourceWithImportState = &ACMEAccountResource{}
+var _ resource.ResourceWithModifyPlan = &ACMEAccountResource{}
 
 func NewACMEAccountResource() resource.Resource {
 	return &ACMEAccountResource{}
@@ -29,7 +30,7 @@ func NewACMEAccountResource() resource.Resource {
 
 // ACMEAccountResource defines the resource implementation.
 type ACMEAccountResource struct {
-	client *proxmox.Client
+	provider *providerData
 }
 
 // ACMEAccountResource describes the resource data model.
@@ -43,15 +44,20 @@ type ACMEAccountResourceModel struct {
 	// Optional attributes
 	Directory types.String `tfsdk:"directory"`
 	TOSurl    types.String `tfsdk:"tos_url"`
+
+	Auth     types.String   `tfsdk:"auth"`
+	Timeouts timeouts.Value `tfsdk:"timeouts"`
 }
 
+func (r *ACMEAccountResource) typeName() string { return "acme_account" }
+
 func (r *ACMEAccountResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
-	resp.TypeName = req.ProviderTypeName + "_acme_account"
+	resp.TypeName = req.ProviderTypeName + "_" + r.typeName()
 }
 
 func (r *ACMEAccountResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
 	resp.Schema = schema.Schema{
-		MarkdownDescription: "Define an ACME account with CA." + docRequiresRoot,
+		MarkdownDescription: "Define an ACME account with CA." + docTicketByDefault,
 		Attributes: map[string]schema.Attribute{
 			"id": schema.StringAttribute{
 				Computed: true,
@@ -75,46 +81,41 @@ func (r *ACMEAccountResource) Schema(ctx context.Context, req resource.SchemaReq
 				PlanModifiers:       []planmodifier.String{stringplanmodifier.RequiresReplace()},
 				MarkdownDescription: "URL of CA TermsOfService - setting this indicates agreement.",
 			},
+			"auth": authAttribute(authTicket),
+		},
+		Blocks: map[string]schema.Block{
+			"timeouts": timeoutsBlock(ctx),
 		},
 	}
 }
 
 func (r *ACMEAccountResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
-	// Prevent panic if the provider has not been configured.
-	if req.ProviderData == nil {
-		return
-	}
-
-	clientFunc, ok := req.ProviderData.(map[string]getClientFunc)["root"]
+	r.provider = configureProviderData(req.ProviderData, &resp.Diagnostics)
+}
 
-	if !ok {
-		resp.Diagnostics.AddError(
-			"Unexpected Data Source Configure Type",
-			fmt.Sprintf("Expected *proxmox.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
-		)
+func (r *ACMEAccountResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	r.provider.checkPrivileges(ctx, r.typeName(), authTicket, sysModifyPrivileges, req, resp)
+}
 
+func (r *ACMEAccountResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data *ACMEAccountResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	client, err := clientFunc()
-	if err != nil {
-		resp.Diagnostics.AddError(
-			"Unable to instantiate client",
-			err.Error(),
-		)
+	ctx, cancel := withTimeout(ctx, data.Timeouts.Create, defaultCreateTimeout, &resp.Diagnostics)
+	defer cancel()
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
-	r.client = client
-}
-
-func (r *ACMEAccountResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
-	var data *ACMEAccountResourceModel
-	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	client := r.provider.client(ctx, r.typeName(), data.Auth, authTicket, &resp.Diagnostics)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	postReq := account.PostRequest{Client: r.client, Contact: data.Contact.ValueString()}
+	postReq := account.PostRequest{Client: withContext(ctx, client), Contact: data.Contact.ValueString()}
 	postReq.Name = data.Name.ValueString()
 	if !data.Directory.IsNull() {
 		postReq.Directory = helpers.PtrTo(data.Directory.ValueString())
@@ -122,16 +123,22 @@ func (r *ACMEAccountResource) Create(ctx context.Context, req resource.CreateReq
 	if !data.TOSurl.IsNull() {
 		postReq.TOSurl = helpers.PtrTo(data.TOSurl.ValueString())
 	}
-	_, err := postReq.Post()
+	upid, err := postReq.Post()
 	if err != nil {
-		resp.Diagnostics.AddError("Error creating acme_account", err.Error())
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error creating acme_account", err, nil)
+		return
+	}
+	if err := waitForTask(ctx, client, string(*upid)); err != nil {
+		resp.Diagnostics.AddError("Error registering acme_account", err.Error())
 		return
 	}
 
-	if _, err := r.eventuallyGet(ctx, data, 5*time.Second); err != nil {
-		resp.Diagnostics.AddError(fmt.Sprintf("acme_account.%s not created", data.Name.ValueString()), err.Error())
+	account, err := account.ItemGetRequest{Client: withContext(ctx, client), Name: data.Name.ValueString()}.Get()
+	if err != nil {
+		addAPIErrorDiagnostics(&resp.Diagnostics, fmt.Sprintf("error reading acme_account.%s", data.Name.ValueString()), err, nil)
 		return
 	}
+	r.convertAPIGetResponseToTerraform(ctx, *account, data)
 
 	tflog.Trace(ctx, "created resource")
 
@@ -146,13 +153,24 @@ func (r *ACMEAccountResource) Read(ctx context.Context, req resource.ReadRequest
 		return
 	}
 
-	account, err := r.eventuallyGet(ctx, data, 5*time.Second)
+	ctx, cancel := withTimeout(ctx, data.Timeouts.Read, defaultReadTimeout, &resp.Diagnostics)
+	defer cancel()
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client := r.provider.client(ctx, r.typeName(), data.Auth, authTicket, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	account, err := account.ItemGetRequest{Client: withContext(ctx, client), Name: data.Name.ValueString()}.Get()
 	if err != nil {
-		if strings.Contains(err.Error(), fmt.Sprintf("ACME account config file '%s' does not exist", data.Name.ValueString())) {
+		if isNotFound(err, data.Name.ValueString()) {
 			resp.State.RemoveResource(ctx)
 			return
 		} else {
-			resp.Diagnostics.AddError(fmt.Sprintf("error reading acme_account.%s", data.Name.ValueString()), err.Error())
+			addAPIErrorDiagnostics(&resp.Diagnostics, fmt.Sprintf("error reading acme_account.%s", data.Name.ValueString()), err, nil)
 			return
 		}
 	}
@@ -169,10 +187,25 @@ func (r *ACMEAccountResource) Update(ctx context.Context, req resource.UpdateReq
 		return
 	}
 
-	putReq := account.ItemPutRequest{Client: r.client, Name: data.Name.ValueString()}
+	ctx, cancel := withTimeout(ctx, data.Timeouts.Update, defaultUpdateTimeout, &resp.Diagnostics)
+	defer cancel()
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client := r.provider.client(ctx, r.typeName(), data.Auth, authTicket, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	putReq := account.ItemPutRequest{Client: withContext(ctx, client), Name: data.Name.ValueString()}
 	putReq.Contact = data.Contact.ValueString()
-	_, err := putReq.Put()
+	upid, err := putReq.Put()
 	if err != nil {
+		addAPIErrorDiagnostics(&resp.Diagnostics, fmt.Sprintf("error updating acme_account.%s", data.Name.ValueString()), err, nil)
+		return
+	}
+	if err := waitForTask(ctx, client, string(*upid)); err != nil {
 		resp.Diagnostics.AddError(fmt.Sprintf("error updating acme_account.%s", data.Name.ValueString()), err.Error())
 		return
 	}
@@ -187,9 +220,27 @@ func (r *ACMEAccountResource) Delete(ctx context.Context, req resource.DeleteReq
 		return
 	}
 
-	err := account.ItemDeleteRequest{Client: r.client, Name: data.Name.ValueString()}.Delete()
+	ctx, cancel := withTimeout(ctx, data.Timeouts.Delete, defaultDeleteTimeout, &resp.Diagnostics)
+	defer cancel()
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client := r.provider.client(ctx, r.typeName(), data.Auth, authTicket, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	upid, err := r.deactivate(withContext(ctx, client), data.Name.ValueString())
 	if err != nil {
-		resp.Diagnostics.AddError("Error deleting acme_account", err.Error())
+		if isNotFound(err, data.Name.ValueString()) {
+			return
+		}
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error deleting acme_account", err, nil)
+		return
+	}
+	if err := waitForTask(ctx, client, upid); err != nil {
+		resp.Diagnostics.AddError("Error deactivating acme_account", err.Error())
 		return
 	}
 }
@@ -200,44 +251,23 @@ func (r *ACMEAccountResource) ImportState(ctx context.Context, req resource.Impo
 
 func (r *ACMEAccountResource) convertAPIGetResponseToTerraform(ctx context.Context, apiData account.ItemGetResponse, tfData *ACMEAccountResourceModel) {
 	tfData.ID = types.StringValue(tfData.Name.ValueString())
-	tfData.Contact = types.StringValue(strings.TrimPrefix(apiData.Account.Contact[0], "mailto:"))
+	if len(apiData.Account.Contact) > 0 {
+		tfData.Contact = types.StringValue(strings.TrimPrefix(apiData.Account.Contact[0], "mailto:"))
+	}
 	tfData.Directory = types.StringValue(apiData.Directory)
 	tfData.TOSurl = types.StringValue(apiData.TOS)
 }
 
-func (r *ACMEAccountResource) eventuallyGet(ctx context.Context, data *ACMEAccountResourceModel, timeout time.Duration) (*account.ItemGetResponse, error) {
-	accChan := make(chan *account.ItemGetResponse, 1)
-	var err error
-
-	go func() {
-		var acc *account.ItemGetResponse
-		elapsedTime := 0 * time.Second
-		for {
-			acc, err = account.ItemGetRequest{Client: r.client, Name: data.Name.ValueString()}.Get()
-			if err == nil &&
-				acc.Directory != "" &&
-				acc.Location != "" &&
-				acc.TOS != "" &&
-				acc.Account.Contact != nil {
-				accChan <- acc
-			}
-			var wait time.Duration
-			if elapsedTime < 5*time.Second {
-				wait = 1 * time.Second
-			} else {
-				wait = 5 * time.Second
-			}
-			time.Sleep(wait)
-			elapsedTime += wait
-			fmt.Fprintf(os.Stderr, "Waiting for proxmoxve_acme_account.%s to converge... (%s)\n", data.Name.ValueString(), elapsedTime)
-		}
-	}()
-
-	select {
-	case acc := <-accChan:
-		r.convertAPIGetResponseToTerraform(ctx, *acc, data)
-		return acc, nil
-	case <-time.After(timeout):
-		return nil, err
+// deactivate deactivates the account with the ACME directory and deletes it,
+// returning the UPID of the task doing so, which account.ItemDeleteRequest
+// drops.
+func (r *ACMEAccountResource) deactivate(client *proxmox.Client, name string) (string, error) {
+	apiURL := client.APIurl
+	apiURL.Path += "/cluster/acme/account/" + name
+	body, err := client.Delete(&apiURL)
+	if err != nil {
+		return "", err
 	}
+	var upid string
+	return upid, json.Unmarshal(body, &upid)
 }
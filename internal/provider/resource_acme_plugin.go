@@ -3,16 +3,13 @@ package provider
 import (
 	"context"
 	"encoding/base64"
-	"errors"
 	"fmt"
-	"os"
 	"strings"
-	"time"
 
-	proxmox "github.com/c10l/proxmoxve-client-go/api"
 	"github.com/c10l/proxmoxve-client-go/api/cluster/acme/plugins"
 	"github.com/c10l/proxmoxve-client-go/helpers"
 	pvetypes "github.com/c10l/proxmoxve-client-go/helpers/types"
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
 	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
@@ -26,6 +23,7 @@ import (
 // Ensure provider defined types fully satisfy framework interfaces
 var _ resource.Resource = &ACMEPluginResource{}
 var _ resource.ResourceWithImportState = &ACMEPluginResource{}
+var _ resource.ResourceWithModifyPlan = &ACMEPluginResource{}
 
 func NewACMEPluginResource() resource.Resource {
 	return &ACMEPluginResource{}
@@ -33,7 +31,7 @@ func NewACMEPluginResource() resource.Resource {
 
 // ACMEPluginResource defines the resource implementation.
 type ACMEPluginResource struct {
-	client *proxmox.Client
+	provider *providerData
 }
 
 // ACMEPluginResource describes the resource data model.
@@ -49,14 +47,27 @@ type ACMEPluginResourceModel struct {
 	Data    types.String `tfsdk:"data"`
 	Disable types.Bool   `tfsdk:"disable"`
 	Nodes   types.Set    `tfsdk:"nodes"`
+
+	Auth     types.String   `tfsdk:"auth"`
+	Timeouts timeouts.Value `tfsdk:"timeouts"`
 }
 
+// acmePluginAPIParams maps the API parameters whose name differs from their attribute.
+var acmePluginAPIParams = map[string]string{"id": "name"}
+
+// acmePluginVersions: ACME plugins, i.e. DNS challenges, were introduced by
+// Proxmox VE 6.2.
+var acmePluginVersions = versionRequirements{Resource: pveRelease{Major: 6, Minor: 2}}
+
+func (r *ACMEPluginResource) typeName() string { return "acme_plugin" }
+
 func (r *ACMEPluginResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
-	resp.TypeName = req.ProviderTypeName + "_acme_plugin"
+	resp.TypeName = req.ProviderTypeName + "_" + r.typeName()
 }
 
 func (r *ACMEPluginResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
 	resp.Schema = schema.Schema{
+		MarkdownDescription: "Define an ACME challenge plugin." + docTicketByDefault,
 		Attributes: map[string]schema.Attribute{
 			"id": schema.StringAttribute{
 				Computed: true,
@@ -84,46 +95,42 @@ func (r *ACMEPluginResource) Schema(ctx context.Context, req resource.SchemaRequ
 				Optional:    true,
 				Computed:    true,
 			},
+			"auth": authAttribute(authTicket),
+		},
+		Blocks: map[string]schema.Block{
+			"timeouts": timeoutsBlock(ctx),
 		},
 	}
 }
 
 func (r *ACMEPluginResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
-	// Prevent panic if the provider has not been configured.
-	if req.ProviderData == nil {
-		return
-	}
-
-	clientFunc, ok := req.ProviderData.(map[string]getClientFunc)["root"]
+	r.provider = configureProviderData(req.ProviderData, &resp.Diagnostics)
+}
 
-	if !ok {
-		resp.Diagnostics.AddError(
-			"Unexpected Data Source Configure Type",
-			fmt.Sprintf("Expected *proxmox.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
-		)
+func (r *ACMEPluginResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	r.provider.checkPrivileges(ctx, r.typeName(), authTicket, sysModifyPrivileges, req, resp)
+	r.provider.checkVersion(ctx, r.typeName(), acmePluginVersions, req, resp)
+}
 
+func (r *ACMEPluginResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data *ACMEPluginResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	client, err := clientFunc()
-	if err != nil {
-		resp.Diagnostics.AddError(
-			"Unable to instantiate client",
-			err.Error(),
-		)
+	ctx, cancel := withTimeout(ctx, data.Timeouts.Create, defaultCreateTimeout, &resp.Diagnostics)
+	defer cancel()
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
-	r.client = client
-}
-
-func (r *ACMEPluginResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
-	var data *ACMEPluginResourceModel
-	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	client := r.provider.client(ctx, r.typeName(), data.Auth, authTicket, &resp.Diagnostics)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	postReq := plugins.PostRequest{Client: r.client, ID: data.Name.ValueString(), Type: data.Type.ValueString()}
+	postReq := plugins.PostRequest{Client: withContext(ctx, client), ID: data.Name.ValueString(), Type: data.Type.ValueString()}
 	if !data.API.IsNull() {
 		postReq.API = helpers.PtrTo(data.API.ValueString())
 	}
@@ -139,7 +146,7 @@ func (r *ACMEPluginResource) Create(ctx context.Context, req resource.CreateRequ
 	}
 	err := postReq.Post()
 	if err != nil {
-		resp.Diagnostics.AddError("Error creating acme_plugin", err.Error())
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error creating acme_plugin", err, acmePluginAPIParams)
 		return
 	}
 
@@ -155,13 +162,24 @@ func (r *ACMEPluginResource) Read(ctx context.Context, req resource.ReadRequest,
 		return
 	}
 
-	plugin, err := r.eventuallyGet(ctx, data, 5*time.Second)
+	ctx, cancel := withTimeout(ctx, data.Timeouts.Read, defaultReadTimeout, &resp.Diagnostics)
+	defer cancel()
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client := r.provider.client(ctx, r.typeName(), data.Auth, authTicket, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	plugin, err := plugins.ItemGetRequest{Client: withContext(ctx, client), ID: data.Name.ValueString()}.Get()
 	if err != nil {
-		if strings.Contains(err.Error(), fmt.Sprintf("ACME plugin '%s' does not exist", data.ID.ValueString())) {
+		if isNotFound(err, data.Name.ValueString()) {
 			resp.State.RemoveResource(ctx)
 			return
 		} else {
-			resp.Diagnostics.AddError(fmt.Sprintf("error reading acme_plugin.%s", data.Name.ValueString()), err.Error())
+			addAPIErrorDiagnostics(&resp.Diagnostics, fmt.Sprintf("error reading acme_plugin.%s", data.Name.ValueString()), err, nil)
 			return
 		}
 	}
@@ -177,7 +195,18 @@ func (r *ACMEPluginResource) Update(ctx context.Context, req resource.UpdateRequ
 		return
 	}
 
-	putReq := plugins.ItemPutRequest{Client: r.client, ID: data.Name.ValueString()}
+	ctx, cancel := withTimeout(ctx, data.Timeouts.Update, defaultUpdateTimeout, &resp.Diagnostics)
+	defer cancel()
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client := r.provider.client(ctx, r.typeName(), data.Auth, authTicket, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	putReq := plugins.ItemPutRequest{Client: withContext(ctx, client), ID: data.Name.ValueString()}
 	delete := []string{}
 	if data.API.IsNull() {
 		delete = append(delete, "api")
@@ -202,7 +231,7 @@ func (r *ACMEPluginResource) Update(ctx context.Context, req resource.UpdateRequ
 	}
 	putReq.Delete = helpers.PtrTo(strings.Join(delete, ","))
 	if err := putReq.Put(); err != nil {
-		resp.Diagnostics.AddError(fmt.Sprintf("error updating acme_account.%s", data.Name.ValueString()), err.Error())
+		addAPIErrorDiagnostics(&resp.Diagnostics, fmt.Sprintf("error updating acme_plugin.%s", data.Name.ValueString()), err, acmePluginAPIParams)
 		return
 	}
 
@@ -216,9 +245,23 @@ func (r *ACMEPluginResource) Delete(ctx context.Context, req resource.DeleteRequ
 		return
 	}
 
-	err := plugins.ItemDeleteRequest{Client: r.client, ID: data.ID.ValueString()}.Delete()
+	ctx, cancel := withTimeout(ctx, data.Timeouts.Delete, defaultDeleteTimeout, &resp.Diagnostics)
+	defer cancel()
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client := r.provider.client(ctx, r.typeName(), data.Auth, authTicket, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := plugins.ItemDeleteRequest{Client: withContext(ctx, client), ID: data.ID.ValueString()}.Delete()
 	if err != nil {
-		resp.Diagnostics.AddError("Error deleting acme_plugin", err.Error())
+		if isNotFound(err, data.ID.ValueString()) {
+			return
+		}
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error deleting acme_plugin", err, nil)
 		return
 	}
 }
@@ -246,41 +289,3 @@ func (r *ACMEPluginResource) convertAPIGetResponseToTerraform(ctx context.Contex
 
 	return diags
 }
-
-func (r *ACMEPluginResource) eventuallyGet(ctx context.Context, data *ACMEPluginResourceModel, timeout time.Duration) (*plugins.ItemGetResponse, error) {
-	accChan := make(chan *plugins.ItemGetResponse, 1)
-	var err error
-
-	go func() {
-		var acc *plugins.ItemGetResponse
-		elapsedTime := 0 * time.Second
-		for {
-			acc, err = plugins.ItemGetRequest{Client: r.client, ID: data.Name.ValueString()}.Get()
-			if err == nil {
-				accChan <- acc
-			}
-			var wait time.Duration
-			if elapsedTime < 5*time.Second {
-				wait = 1 * time.Second
-			} else {
-				wait = 5 * time.Second
-			}
-			time.Sleep(wait)
-			elapsedTime += wait
-			fmt.Fprintf(os.Stderr, "Waiting for proxmoxve_acme_plugins.%s to be created... (%s)\n", data.Name.ValueString(), elapsedTime)
-		}
-	}()
-
-	select {
-	case acc := <-accChan:
-		diags := r.convertAPIGetResponseToTerraform(ctx, *acc, data)
-		if diags.HasError() {
-			for _, err := range diags.Errors() {
-				return nil, errors.New(err.Detail())
-			}
-		}
-		return acc, nil
-	case <-time.After(timeout):
-		return nil, err
-	}
-}
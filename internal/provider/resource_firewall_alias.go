@@ -4,9 +4,9 @@ import (
 	"context"
 	"fmt"
 
-	proxmox "github.com/c10l/proxmoxve-client-go/api"
 	"github.com/c10l/proxmoxve-client-go/api/cluster/firewall/aliases"
 	"github.com/c10l/proxmoxve-client-go/helpers"
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
@@ -16,6 +16,7 @@ import (
 // Ensure provider defined types fully satisfy framework interfaces
 var _ resource.Resource = &FirewallAliasResource{}
 var _ resource.ResourceWithImportState = &FirewallAliasResource{}
+var _ resource.ResourceWithModifyPlan = &FirewallAliasResource{}
 
 func NewFirewallAliasResource() resource.Resource {
 	return &FirewallAliasResource{}
@@ -23,7 +24,7 @@ func NewFirewallAliasResource() resource.Resource {
 
 // FirewallAliasResource defines the resource implementation.
 type FirewallAliasResource struct {
-	client *proxmox.Client
+	provider *providerData
 }
 
 // FirewallAliasResource describes the resource data model.
@@ -32,10 +33,15 @@ type FirewallAliasResourceModel struct {
 	Name    types.String `tfsdk:"name"`
 	CIDR    types.String `tfsdk:"cidr"`
 	Comment types.String `tfsdk:"comment"`
+
+	Auth     types.String   `tfsdk:"auth"`
+	Timeouts timeouts.Value `tfsdk:"timeouts"`
 }
 
+func (r *FirewallAliasResource) typeName() string { return "firewall_alias" }
+
 func (r *FirewallAliasResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
-	resp.TypeName = req.ProviderTypeName + "_firewall_alias"
+	resp.TypeName = req.ProviderTypeName + "_" + r.typeName()
 }
 
 func (r *FirewallAliasResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
@@ -53,58 +59,54 @@ func (r *FirewallAliasResource) Schema(ctx context.Context, req resource.SchemaR
 			"comment": schema.StringAttribute{
 				Optional: true,
 			},
+			"auth": authAttribute(authToken),
+		},
+		Blocks: map[string]schema.Block{
+			"timeouts": timeoutsBlock(ctx),
 		},
 	}
 }
 
 func (r *FirewallAliasResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
-	// Prevent panic if the provider has not been configured.
-	if req.ProviderData == nil {
-		return
-	}
-
-	clientFunc, ok := req.ProviderData.(map[string]getClientFunc)["token"]
+	r.provider = configureProviderData(req.ProviderData, &resp.Diagnostics)
+}
 
-	if !ok {
-		resp.Diagnostics.AddError(
-			"Unexpected Data Source Configure Type",
-			fmt.Sprintf("Expected *proxmox.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
-		)
+func (r *FirewallAliasResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	r.provider.checkPrivileges(ctx, r.typeName(), authToken, sysModifyPrivileges, req, resp)
+}
 
+func (r *FirewallAliasResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data *FirewallAliasResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	client, err := clientFunc()
-	if err != nil {
-		resp.Diagnostics.AddError(
-			"Unable to instantiate client",
-			err.Error(),
-		)
+	ctx, cancel := withTimeout(ctx, data.Timeouts.Create, defaultCreateTimeout, &resp.Diagnostics)
+	defer cancel()
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
-	r.client = client
-}
-
-func (r *FirewallAliasResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
-	var data *FirewallAliasResourceModel
-	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	client := r.provider.client(ctx, r.typeName(), data.Auth, authToken, &resp.Diagnostics)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	postReq := aliases.PostRequest{Client: r.client, Name: data.Name.ValueString(), CIDR: data.CIDR.ValueString()}
+	postReq := aliases.PostRequest{Client: withContext(ctx, client), Name: data.Name.ValueString(), CIDR: data.CIDR.ValueString()}
 	if !data.Comment.IsNull() {
 		postReq.Comment = helpers.PtrTo(data.Comment.ValueString())
 	}
-	err := postReq.Post()
+	// The creation of an alias takes no digest.
+	err := mutateFirewall(ctx, client, clusterFirewall, "", func(string) error { return postReq.Post() })
 	if err != nil {
-		resp.Diagnostics.AddError("Error creating firewall_alias", err.Error())
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error creating firewall_alias", err, nil)
 		return
 	}
 
-	getResp, err := aliases.ItemGetRequest{Client: r.client, Name: data.Name.ValueString()}.Get()
+	getResp, err := aliases.ItemGetRequest{Client: withContext(ctx, client), Name: data.Name.ValueString()}.Get()
 	if err != nil {
-		resp.Diagnostics.AddError("Error retrieving firewall_alias", err.Error())
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error retrieving firewall_alias", err, nil)
 		return
 	}
 	r.convertAPIGetResponseToTerraform(ctx, *getResp, data)
@@ -119,9 +121,25 @@ func (r *FirewallAliasResource) Read(ctx context.Context, req resource.ReadReque
 		return
 	}
 
-	alias, err := aliases.ItemGetRequest{Client: r.client, Name: data.Name.ValueString()}.Get()
+	ctx, cancel := withTimeout(ctx, data.Timeouts.Read, defaultReadTimeout, &resp.Diagnostics)
+	defer cancel()
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client := r.provider.client(ctx, r.typeName(), data.Auth, authToken, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	alias, err := aliases.ItemGetRequest{Client: withContext(ctx, client), Name: data.Name.ValueString()}.Get()
 	if err != nil {
-		resp.Diagnostics.AddError(fmt.Sprintf("Error reading firewall_alias.%s", data.Name.ValueString()), err.Error())
+		// If resource has been deleted outside of Terraform, we remove it from the plan state so it can be re-created.
+		if isNotFound(err, data.Name.ValueString()) {
+			resp.State.RemoveResource(ctx)
+			return
+		}
+		addAPIErrorDiagnostics(&resp.Diagnostics, fmt.Sprintf("Error reading firewall_alias.%s", data.Name.ValueString()), err, nil)
 		return
 	}
 
@@ -143,22 +161,36 @@ func (r *FirewallAliasResource) Update(ctx context.Context, req resource.UpdateR
 		return
 	}
 
-	putReq := aliases.ItemPutRequest{Client: r.client, Name: state.Name.ValueString(), CIDR: config.CIDR.ValueString()}
+	ctx, cancel := withTimeout(ctx, config.Timeouts.Update, defaultUpdateTimeout, &resp.Diagnostics)
+	defer cancel()
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client := r.provider.client(ctx, r.typeName(), config.Auth, authToken, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	putReq := aliases.ItemPutRequest{Client: withContext(ctx, client), Name: state.Name.ValueString(), CIDR: config.CIDR.ValueString()}
 	if state.Name.ValueString() != config.Name.ValueString() {
 		putReq.Rename = helpers.PtrTo(config.Name.ValueString())
 	}
 	if !config.Comment.IsNull() {
 		putReq.Comment = helpers.PtrTo(config.Comment.ValueString())
 	}
-	err := putReq.Put()
+	err := mutateFirewall(ctx, client, clusterFirewall, clusterFirewall+"/aliases", func(digest string) error {
+		putReq.Digest = digestParam(digest)
+		return putReq.Put()
+	})
 	if err != nil {
-		resp.Diagnostics.AddError(fmt.Sprintf("error updating firewall_alias.%s", state.Name.ValueString()), err.Error())
+		addAPIErrorDiagnostics(&resp.Diagnostics, fmt.Sprintf("error updating firewall_alias.%s", state.Name.ValueString()), err, map[string]string{"rename": "name"})
 		return
 	}
 
-	getResp, err := aliases.ItemGetRequest{Client: r.client, Name: config.Name.ValueString()}.Get()
+	getResp, err := aliases.ItemGetRequest{Client: withContext(ctx, client), Name: config.Name.ValueString()}.Get()
 	if err != nil {
-		resp.Diagnostics.AddError("Error retrieving firewall_alias", err.Error())
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error retrieving firewall_alias", err, nil)
 		return
 	}
 	r.convertAPIGetResponseToTerraform(ctx, *getResp, config)
@@ -173,9 +205,27 @@ func (r *FirewallAliasResource) Delete(ctx context.Context, req resource.DeleteR
 		return
 	}
 
-	err := aliases.ItemDeleteRequest{Client: r.client, Name: data.Name.ValueString()}.Delete()
+	ctx, cancel := withTimeout(ctx, data.Timeouts.Delete, defaultDeleteTimeout, &resp.Diagnostics)
+	defer cancel()
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client := r.provider.client(ctx, r.typeName(), data.Auth, authToken, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := mutateFirewall(ctx, client, clusterFirewall, clusterFirewall+"/aliases", func(digest string) error {
+		// ItemDeleteRequest.Delete does not send its Digest.
+		deleteReq := aliases.ItemDeleteRequest{Client: withContext(ctx, client), Name: data.Name.ValueString()}
+		return deleteReq.Client.DeleteItem(deleteReq, clusterFirewall+"/aliases", deleteReq.Name, digest)
+	})
 	if err != nil {
-		resp.Diagnostics.AddError("Error deleting firewall_alias", err.Error())
+		if isNotFound(err, data.Name.ValueString()) {
+			return
+		}
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error deleting firewall_alias", err, nil)
 		return
 	}
 }
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"testing"
 
+	proxmox "github.com/c10l/proxmoxve-client-go/api"
+	"github.com/c10l/proxmoxve-client-go/api/cluster/firewall/aliases"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
 )
 
@@ -19,13 +21,16 @@ func TestFirewallAliasResource(t *testing.T) {
 					resource.TestCheckResourceAttr("proxmoxve_firewall_alias.test", "name", "pmve_firewall_alias_test"),
 					resource.TestCheckResourceAttr("proxmoxve_firewall_alias.test", "cidr", "1.2.3.0/24"),
 					resource.TestCheckResourceAttr("proxmoxve_firewall_alias.test", "comment", "this is a comment"),
+					resource.TestCheckResourceAttr("proxmoxve_firewall_alias.test", "timeouts.create", "2m"),
+					resource.TestCheckResourceAttr("proxmoxve_firewall_alias.test", "auth", "token"),
 				),
 			},
 			// ImportState testing
 			{
-				ResourceName:      "proxmoxve_firewall_alias.test",
-				ImportState:       true,
-				ImportStateVerify: true,
+				ResourceName:            "proxmoxve_firewall_alias.test",
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"timeouts", "auth"},
 			},
 			// Update and Read testing
 			{
@@ -47,6 +52,17 @@ func TestFirewallAliasResource(t *testing.T) {
 					resource.TestCheckResourceAttr("proxmoxve_firewall_alias.test", "comment", "no comments"),
 				),
 			},
+			// Deleted outside of Terraform and re-created
+			{
+				PreConfig: testAccDeleteOutOfBand(t, func(c *proxmox.Client) error {
+					return aliases.ItemDeleteRequest{Client: c, Name: "pmve_firewall_alias_test_renamed"}.Delete()
+				}),
+				Config: testAccFirewallAliasResourceConfig("pmve_firewall_alias_test_renamed", "4.5.0.0/16", "no comments"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("proxmoxve_firewall_alias.test", "name", "pmve_firewall_alias_test_renamed"),
+					resource.TestCheckResourceAttr("proxmoxve_firewall_alias.test", "cidr", "4.5.0.0/16"),
+				),
+			},
 			// Delete testing automatically occurs in TestCase
 		},
 	})
@@ -58,6 +74,12 @@ func testAccFirewallAliasResourceConfig(name, cidr, comment string) string {
 			name    = "%s"
 			cidr    = "%s"
 			comment = "%s"
+			auth    = "token"
+
+			timeouts {
+				create = "2m"
+				read   = "30s"
+			}
 		}
 	`, name, cidr, comment)
 }
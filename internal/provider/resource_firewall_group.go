@@ -2,11 +2,11 @@ package provider
 
 import (
 	"context"
-	"fmt"
 
 	proxmox "github.com/c10l/proxmoxve-client-go/api"
 	"github.com/c10l/proxmoxve-client-go/api/cluster/firewall/groups"
 	"github.com/c10l/proxmoxve-client-go/helpers"
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
 	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
@@ -19,6 +19,7 @@ import (
 // Ensure provider defined types fully satisfy framework interfaces
 var _ resource.Resource = &FirewallGroupResource{}
 var _ resource.ResourceWithImportState = &FirewallGroupResource{}
+var _ resource.ResourceWithModifyPlan = &FirewallGroupResource{}
 
 func NewFirewallGroupResource() resource.Resource {
 	return &FirewallGroupResource{}
@@ -26,7 +27,7 @@ func NewFirewallGroupResource() resource.Resource {
 
 // FirewallGroupResource defines the resource implementation.
 type FirewallGroupResource struct {
-	client *proxmox.Client
+	provider *providerData
 }
 
 // FirewallGroupResource describes the resource data model.
@@ -34,8 +35,14 @@ type FirewallGroupResourceModel struct {
 	ID      types.String `tfsdk:"id"`
 	Name    types.String `tfsdk:"name"`
 	Comment types.String `tfsdk:"comment"`
+
+	Auth     types.String   `tfsdk:"auth"`
+	Timeouts timeouts.Value `tfsdk:"timeouts"`
 }
 
+// firewallGroupAPIParams maps the API parameters whose name differs from their attribute.
+var firewallGroupAPIParams = map[string]string{"group": "name", "rename": "name"}
+
 func (r *FirewallGroupResource) typeName() string { return "firewall_group" }
 
 func (r *FirewallGroupResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
@@ -56,50 +63,48 @@ func (r *FirewallGroupResource) Schema(ctx context.Context, req resource.SchemaR
 			"comment": schema.StringAttribute{
 				Optional: true,
 			},
+			"auth": authAttribute(authToken),
+		},
+		Blocks: map[string]schema.Block{
+			"timeouts": timeoutsBlock(ctx),
 		},
 	}
 }
 
 func (r *FirewallGroupResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
-	// Prevent panic if the provider has not been configured.
-	if req.ProviderData == nil {
-		return
-	}
-
-	clientFunc, ok := req.ProviderData.(map[string]getClientFunc)["token"]
+	r.provider = configureProviderData(req.ProviderData, &resp.Diagnostics)
+}
 
-	if !ok {
-		resp.Diagnostics.AddError(
-			"Unexpected Data Source Configure Type",
-			fmt.Sprintf("Expected *proxmox.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
-		)
+func (r *FirewallGroupResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	r.provider.checkPrivileges(ctx, r.typeName(), authToken, sysModifyPrivileges, req, resp)
+}
 
+func (r *FirewallGroupResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data *FirewallGroupResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	client, err := clientFunc()
-	if err != nil {
-		resp.Diagnostics.AddError(
-			"Unable to instantiate client",
-			err.Error(),
-		)
+	ctx, cancel := withTimeout(ctx, data.Timeouts.Create, defaultCreateTimeout, &resp.Diagnostics)
+	defer cancel()
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
-	r.client = client
-}
-
-func (r *FirewallGroupResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
-	var data *FirewallGroupResourceModel
-	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	client := r.provider.client(ctx, r.typeName(), data.Auth, authToken, &resp.Diagnostics)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	postReq := groups.PostRequest{Client: r.client, Group: data.Name.ValueString()}
+	postReq := groups.PostRequest{Client: withContext(ctx, client), Group: data.Name.ValueString()}
 	postReq.Comment = helpers.PtrTo(data.Comment.ValueString())
-	err := postReq.Post()
+	err := mutateFirewall(ctx, client, clusterFirewall, clusterFirewall+"/groups", func(digest string) error {
+		postReq.Digest = digestParam(digest)
+		return postReq.Post()
+	})
 	if err != nil {
-		resp.Diagnostics.AddError("Error creating "+r.typeName(), err.Error())
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error creating "+r.typeName(), err, firewallGroupAPIParams)
 		return
 	}
 
@@ -114,11 +119,27 @@ func (r *FirewallGroupResource) Read(ctx context.Context, req resource.ReadReque
 		return
 	}
 
-	group := r.findGroupOnList(data.Name.ValueString(), &resp.Diagnostics)
+	ctx, cancel := withTimeout(ctx, data.Timeouts.Read, defaultReadTimeout, &resp.Diagnostics)
+	defer cancel()
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
+	client := r.provider.client(ctx, r.typeName(), data.Auth, authToken, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	group := r.findGroupOnList(ctx, client, data.Name.ValueString(), &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	// If resource has been deleted outside of Terraform, we remove it from the plan state so it can be re-created.
+	if group == nil {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
 	data.Name = types.StringValue(group.Group)
 	data.ID = data.Name
 	if group.Comment != "" {
@@ -138,14 +159,29 @@ func (r *FirewallGroupResource) Update(ctx context.Context, req resource.UpdateR
 		return
 	}
 
-	err := groups.PostRequest{
-		Client:  r.client,
+	ctx, cancel := withTimeout(ctx, config.Timeouts.Update, defaultUpdateTimeout, &resp.Diagnostics)
+	defer cancel()
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client := r.provider.client(ctx, r.typeName(), config.Auth, authToken, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	postReq := groups.PostRequest{
+		Client:  withContext(ctx, client),
 		Group:   config.Name.ValueString(),
 		Rename:  helpers.PtrTo(state.Name.ValueString()),
 		Comment: helpers.PtrTo(config.Comment.ValueString()),
-	}.Post()
+	}
+	err := mutateFirewall(ctx, client, clusterFirewall, clusterFirewall+"/groups", func(digest string) error {
+		postReq.Digest = digestParam(digest)
+		return postReq.Post()
+	})
 	if err != nil {
-		resp.Diagnostics.AddError("Error updating "+r.typeName(), err.Error())
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error updating "+r.typeName(), err, firewallGroupAPIParams)
 		return
 	}
 
@@ -160,9 +196,26 @@ func (r *FirewallGroupResource) Delete(ctx context.Context, req resource.DeleteR
 		return
 	}
 
-	err := groups.ItemDeleteRequest{Client: r.client, Group: data.Name.ValueString()}.Delete()
+	ctx, cancel := withTimeout(ctx, data.Timeouts.Delete, defaultDeleteTimeout, &resp.Diagnostics)
+	defer cancel()
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client := r.provider.client(ctx, r.typeName(), data.Auth, authToken, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// The deletion of a security group takes no digest.
+	err := mutateFirewall(ctx, client, clusterFirewall, "", func(string) error {
+		return groups.ItemDeleteRequest{Client: withContext(ctx, client), Group: data.Name.ValueString()}.Delete()
+	})
 	if err != nil {
-		resp.Diagnostics.AddError("Error deleting "+r.typeName(), err.Error())
+		if isNotFound(err, data.Name.ValueString()) {
+			return
+		}
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error deleting "+r.typeName(), err, nil)
 		return
 	}
 }
@@ -171,20 +224,13 @@ func (r *FirewallGroupResource) ImportState(ctx context.Context, req resource.Im
 	resource.ImportStatePassthroughID(ctx, path.Root("name"), req, resp)
 }
 
-func (r *FirewallGroupResource) findGroupOnList(name string, diags *diag.Diagnostics) *groups.GetResponse {
-	groupList, err := groups.GetRequest{Client: r.client}.Get()
+// findGroupOnList returns the security group called name, or nil if there is none.
+func (r *FirewallGroupResource) findGroupOnList(ctx context.Context, client *proxmox.Client, name string, diags *diag.Diagnostics) *groups.GetResponse {
+	groupList, err := groups.GetRequest{Client: withContext(ctx, client)}.Get()
 	if err != nil {
-		diags.AddError("Error getting Group list", err.Error())
+		addAPIErrorDiagnostics(diags, "Error getting Group list", err, nil)
 		return nil
 	}
 
-	group := groupList.FindByName(name)
-	if group == nil {
-		diags.AddError(
-			fmt.Sprintf("Group %s not found on list", name),
-			fmt.Sprintf("Groups returned from the server:\n%+v", groupList),
-		)
-		return nil
-	}
-	return group
+	return groupList.FindByName(name)
 }
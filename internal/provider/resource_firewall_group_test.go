@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"testing"
 
+	proxmox "github.com/c10l/proxmoxve-client-go/api"
+	"github.com/c10l/proxmoxve-client-go/api/cluster/firewall/groups"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
 )
 
@@ -42,6 +44,17 @@ func TestFirewallGroupResource(t *testing.T) {
 					resource.TestCheckResourceAttr("proxmoxve_firewall_group.test", "comment", "no comments"),
 				),
 			},
+			// Deleted outside of Terraform and re-created
+			{
+				PreConfig: testAccDeleteOutOfBand(t, func(c *proxmox.Client) error {
+					return groups.ItemDeleteRequest{Client: c, Group: "pmve_fw_group_ren"}.Delete()
+				}),
+				Config: testAccFirewallGroupResourceConfig("pmve_fw_group_ren", "no comments"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("proxmoxve_firewall_group.test", "name", "pmve_fw_group_ren"),
+					resource.TestCheckResourceAttr("proxmoxve_firewall_group.test", "comment", "no comments"),
+				),
+			},
 			// Delete testing automatically occurs in TestCase
 		},
 	})
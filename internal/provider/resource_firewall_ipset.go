@@ -7,6 +7,7 @@ import (
 	proxmox "github.com/c10l/proxmoxve-client-go/api"
 	"github.com/c10l/proxmoxve-client-go/api/cluster/firewall/ipset"
 	"github.com/c10l/proxmoxve-client-go/helpers"
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
 	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
@@ -17,6 +18,7 @@ import (
 // Ensure provider defined types fully satisfy framework interfaces
 var _ resource.Resource = &FirewallIPSetResource{}
 var _ resource.ResourceWithImportState = &FirewallIPSetResource{}
+var _ resource.ResourceWithModifyPlan = &FirewallIPSetResource{}
 
 func NewFirewallIPSetResource() resource.Resource {
 	return &FirewallIPSetResource{}
@@ -24,7 +26,7 @@ func NewFirewallIPSetResource() resource.Resource {
 
 // FirewallIPSetResource defines the resource implementation.
 type FirewallIPSetResource struct {
-	client *proxmox.Client
+	provider *providerData
 }
 
 // FirewallIPSetResource describes the resource data model.
@@ -32,6 +34,9 @@ type FirewallIPSetResourceModel struct {
 	ID      types.String `tfsdk:"id"`
 	Name    types.String `tfsdk:"name"`
 	Comment types.String `tfsdk:"comment"`
+
+	Auth     types.String   `tfsdk:"auth"`
+	Timeouts timeouts.Value `tfsdk:"timeouts"`
 }
 
 func (r *FirewallIPSetResource) typeName() string { return "firewall_ipset" }
@@ -52,50 +57,48 @@ func (r *FirewallIPSetResource) Schema(ctx context.Context, req resource.SchemaR
 			"comment": schema.StringAttribute{
 				Optional: true,
 			},
+			"auth": authAttribute(authToken),
+		},
+		Blocks: map[string]schema.Block{
+			"timeouts": timeoutsBlock(ctx),
 		},
 	}
 }
 
 func (r *FirewallIPSetResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
-	// Prevent panic if the provider has not been configured.
-	if req.ProviderData == nil {
-		return
-	}
-
-	clientFunc, ok := req.ProviderData.(map[string]getClientFunc)["token"]
+	r.provider = configureProviderData(req.ProviderData, &resp.Diagnostics)
+}
 
-	if !ok {
-		resp.Diagnostics.AddError(
-			"Unexpected Data Source Configure Type",
-			fmt.Sprintf("Expected *proxmox.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
-		)
+func (r *FirewallIPSetResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	r.provider.checkPrivileges(ctx, r.typeName(), authToken, sysModifyPrivileges, req, resp)
+}
 
+func (r *FirewallIPSetResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data *FirewallIPSetResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	client, err := clientFunc()
-	if err != nil {
-		resp.Diagnostics.AddError(
-			"Unable to instantiate client",
-			err.Error(),
-		)
+	ctx, cancel := withTimeout(ctx, data.Timeouts.Create, defaultCreateTimeout, &resp.Diagnostics)
+	defer cancel()
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
-	r.client = client
-}
-
-func (r *FirewallIPSetResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
-	var data *FirewallIPSetResourceModel
-	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	client := r.provider.client(ctx, r.typeName(), data.Auth, authToken, &resp.Diagnostics)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	postReq := ipset.PostRequest{Client: r.client, Name: data.Name.ValueString()}
+	postReq := ipset.PostRequest{Client: withContext(ctx, client), Name: data.Name.ValueString()}
 	postReq.Comment = helpers.PtrTo(data.Comment.ValueString())
-	err := postReq.Post()
+	err := mutateFirewall(ctx, client, clusterFirewall, clusterFirewall+"/ipset", func(digest string) error {
+		postReq.Digest = digestParam(digest)
+		return postReq.Post()
+	})
 	if err != nil {
-		resp.Diagnostics.AddError("Error creating "+r.typeName(), err.Error())
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error creating "+r.typeName(), err, nil)
 		return
 	}
 
@@ -110,10 +113,26 @@ func (r *FirewallIPSetResource) Read(ctx context.Context, req resource.ReadReque
 		return
 	}
 
-	ipSet := r.findIPSetOnList(data.Name.ValueString(), &resp.Diagnostics)
+	ctx, cancel := withTimeout(ctx, data.Timeouts.Read, defaultReadTimeout, &resp.Diagnostics)
+	defer cancel()
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client := r.provider.client(ctx, r.typeName(), data.Auth, authToken, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ipSet := r.findIPSetOnList(ctx, client, data.Name.ValueString(), &resp.Diagnostics)
 	if resp.Diagnostics.HasError() {
 		return
 	}
+	// If resource has been deleted outside of Terraform, we remove it from the plan state so it can be re-created.
+	if ipSet == nil {
+		resp.State.RemoveResource(ctx)
+		return
+	}
 
 	data.ID = types.StringValue(ipSet.Name)
 	if ipSet.Comment != nil {
@@ -133,25 +152,45 @@ func (r *FirewallIPSetResource) Update(ctx context.Context, req resource.UpdateR
 		return
 	}
 
+	ctx, cancel := withTimeout(ctx, config.Timeouts.Update, defaultUpdateTimeout, &resp.Diagnostics)
+	defer cancel()
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client := r.provider.client(ctx, r.typeName(), config.Auth, authToken, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	putReq := ipset.PostRequest{
-		Client:  r.client,
+		Client:  withContext(ctx, client),
 		Name:    config.Name.ValueString(),
 		Rename:  helpers.PtrTo(state.Name.ValueString()),
 		Comment: helpers.PtrTo(config.Comment.ValueString()),
 	}
-	err := putReq.Post()
+	err := mutateFirewall(ctx, client, clusterFirewall, clusterFirewall+"/ipset", func(digest string) error {
+		putReq.Digest = digestParam(digest)
+		return putReq.Post()
+	})
 	if err != nil {
-		resp.Diagnostics.AddError("Error updating "+r.typeName(), err.Error())
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error updating "+r.typeName(), err, map[string]string{"rename": "name"})
 		return
 	}
 
-	ipSet := r.findIPSetOnList(config.Name.ValueString(), &resp.Diagnostics)
+	ipSet := r.findIPSetOnList(ctx, client, config.Name.ValueString(), &resp.Diagnostics)
 	if resp.Diagnostics.HasError() {
 		return
 	}
+	if ipSet == nil {
+		resp.Diagnostics.AddError("Error updating "+r.typeName(), fmt.Sprintf("IPSet %s not found on list", config.Name.ValueString()))
+		return
+	}
 
 	state.ID = types.StringValue(ipSet.Name)
 	state.Name = types.StringValue(ipSet.Name)
+	state.Auth = config.Auth
+	state.Timeouts = config.Timeouts
 	if ipSet.Comment != nil {
 		state.Comment = types.StringValue(*ipSet.Comment)
 	} else {
@@ -167,9 +206,26 @@ func (r *FirewallIPSetResource) Delete(ctx context.Context, req resource.DeleteR
 		return
 	}
 
-	err := ipset.ItemDeleteRequest{Client: r.client, Name: data.Name.ValueString()}.Delete()
+	ctx, cancel := withTimeout(ctx, data.Timeouts.Delete, defaultDeleteTimeout, &resp.Diagnostics)
+	defer cancel()
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client := r.provider.client(ctx, r.typeName(), data.Auth, authToken, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// The deletion of an IPSet takes no digest.
+	err := mutateFirewall(ctx, client, clusterFirewall, "", func(string) error {
+		return ipset.ItemDeleteRequest{Client: withContext(ctx, client), Name: data.Name.ValueString()}.Delete()
+	})
 	if err != nil {
-		resp.Diagnostics.AddError("Error deleting "+r.typeName(), err.Error())
+		if isNotFound(err, data.Name.ValueString()) {
+			return
+		}
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error deleting "+r.typeName(), err, nil)
 		return
 	}
 }
@@ -178,20 +234,13 @@ func (r *FirewallIPSetResource) ImportState(ctx context.Context, req resource.Im
 	resource.ImportStatePassthroughID(ctx, path.Root("name"), req, resp)
 }
 
-func (r *FirewallIPSetResource) findIPSetOnList(name string, diags *diag.Diagnostics) *ipset.GetResponse {
-	ipSetList, err := ipset.GetRequest{Client: r.client}.Get()
+// findIPSetOnList returns the IPSet called name, or nil if there is none.
+func (r *FirewallIPSetResource) findIPSetOnList(ctx context.Context, client *proxmox.Client, name string, diags *diag.Diagnostics) *ipset.GetResponse {
+	ipSetList, err := ipset.GetRequest{Client: withContext(ctx, client)}.Get()
 	if err != nil {
-		diags.AddError("Error getting IPSet list", err.Error())
+		addAPIErrorDiagnostics(diags, "Error getting IPSet list", err, nil)
 		return nil
 	}
 
-	ipSet := ipSetList.FindByName(name)
-	if ipSet == nil {
-		diags.AddError(
-			fmt.Sprintf("IPSet %s not found on list", name),
-			fmt.Sprintf("IPSets returned from the server:\n%+v", ipSetList),
-		)
-		return nil
-	}
-	return ipSet
+	return ipSetList.FindByName(name)
 }
@@ -5,10 +5,10 @@ import (
 	"fmt"
 	"strings"
 
-	proxmox "github.com/c10l/proxmoxve-client-go/api"
 	"github.com/c10l/proxmoxve-client-go/api/cluster/firewall/ipset/ipset_cidr"
 	"github.com/c10l/proxmoxve-client-go/helpers"
 	pvetypes "github.com/c10l/proxmoxve-client-go/helpers/types"
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
@@ -20,6 +20,7 @@ import (
 // Ensure provider defined types fully satisfy framework interfaces
 var _ resource.Resource = &FirewallIPSetCIDRResource{}
 var _ resource.ResourceWithImportState = &FirewallIPSetCIDRResource{}
+var _ resource.ResourceWithModifyPlan = &FirewallIPSetCIDRResource{}
 
 func NewFirewallIPSetCIDRResource() resource.Resource {
 	return &FirewallIPSetCIDRResource{}
@@ -27,7 +28,7 @@ func NewFirewallIPSetCIDRResource() resource.Resource {
 
 // FirewallIPSetCIDRResource defines the resource implementation.
 type FirewallIPSetCIDRResource struct {
-	client *proxmox.Client
+	provider *providerData
 }
 
 // FirewallIPSetCIDRResource describes the resource data model.
@@ -37,8 +38,14 @@ type FirewallIPSetCIDRResourceModel struct {
 	CIDR      types.String `tfsdk:"cidr"`
 	NoMatch   types.Bool   `tfsdk:"no_match"`
 	Comment   types.String `tfsdk:"comment"`
+
+	Auth     types.String   `tfsdk:"auth"`
+	Timeouts timeouts.Value `tfsdk:"timeouts"`
 }
 
+// firewallIPSetCIDRAPIParams maps the API parameters whose name differs from their attribute.
+var firewallIPSetCIDRAPIParams = map[string]string{"name": "ipset_name", "nomatch": "no_match"}
+
 func (r *FirewallIPSetCIDRResource) typeName() string { return "firewall_ipset_cidr" }
 
 func (r *FirewallIPSetCIDRResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
@@ -69,55 +76,51 @@ func (r *FirewallIPSetCIDRResource) Schema(ctx context.Context, req resource.Sch
 			"comment": schema.StringAttribute{
 				Optional: true,
 			},
+			"auth": authAttribute(authToken),
+		},
+		Blocks: map[string]schema.Block{
+			"timeouts": timeoutsBlock(ctx),
 		},
 	}
 }
 
 func (r *FirewallIPSetCIDRResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
-	// Prevent panic if the provider has not been configured.
-	if req.ProviderData == nil {
-		return
-	}
-
-	clientFunc, ok := req.ProviderData.(map[string]getClientFunc)["token"]
+	r.provider = configureProviderData(req.ProviderData, &resp.Diagnostics)
+}
 
-	if !ok {
-		resp.Diagnostics.AddError(
-			"Unexpected Data Source Configure Type",
-			fmt.Sprintf("Expected *proxmox.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
-		)
+func (r *FirewallIPSetCIDRResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	r.provider.checkPrivileges(ctx, r.typeName(), authToken, sysModifyPrivileges, req, resp)
+}
 
+func (r *FirewallIPSetCIDRResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var config *FirewallIPSetCIDRResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	client, err := clientFunc()
-	if err != nil {
-		resp.Diagnostics.AddError(
-			"Unable to instantiate client",
-			err.Error(),
-		)
+	ctx, cancel := withTimeout(ctx, config.Timeouts.Create, defaultCreateTimeout, &resp.Diagnostics)
+	defer cancel()
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
-	r.client = client
-}
-
-func (r *FirewallIPSetCIDRResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
-	var config *FirewallIPSetCIDRResourceModel
-	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	client := r.provider.client(ctx, r.typeName(), config.Auth, authToken, &resp.Diagnostics)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
 	postReq := ipset_cidr.PostRequest{
-		Client:    r.client,
+		Client:    withContext(ctx, client),
 		IPSetName: config.IPSetName.ValueString(),
 		CIDR:      config.CIDR.ValueString(),
 		NoMatch:   helpers.PtrTo(pvetypes.PVEBool(config.NoMatch.ValueBool())),
 		Comment:   helpers.PtrTo(config.Comment.ValueString()),
 	}
-	err := postReq.Post()
+	// The creation of an IPSet entry takes no digest.
+	err := mutateFirewall(ctx, client, clusterFirewall, "", func(string) error { return postReq.Post() })
 	if err != nil {
-		resp.Diagnostics.AddError("Error creating "+r.typeName(), err.Error())
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error creating "+r.typeName(), err, firewallIPSetCIDRAPIParams)
 		return
 	}
 
@@ -133,13 +136,29 @@ func (r *FirewallIPSetCIDRResource) Read(ctx context.Context, req resource.ReadR
 		return
 	}
 
+	ctx, cancel := withTimeout(ctx, state.Timeouts.Read, defaultReadTimeout, &resp.Diagnostics)
+	defer cancel()
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client := r.provider.client(ctx, r.typeName(), state.Auth, authToken, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	ipSetCIDR, err := ipset_cidr.ItemGetRequest{
-		Client:    r.client,
+		Client:    withContext(ctx, client),
 		IPSetName: state.IPSetName.ValueString(),
 		CIDR:      state.CIDR.ValueString(),
 	}.Get()
 	if err != nil {
-		resp.Diagnostics.AddError(fmt.Sprintf("Error reading %s %s", r.typeName(), state.ID.ValueString()), err.Error())
+		// If resource has been deleted outside of Terraform, we remove it from the plan state so it can be re-created.
+		if isNotFound(err, state.IPSetName.ValueString(), state.CIDR.ValueString()) {
+			resp.State.RemoveResource(ctx)
+			return
+		}
+		addAPIErrorDiagnostics(&resp.Diagnostics, fmt.Sprintf("Error reading %s %s", r.typeName(), state.ID.ValueString()), err, nil)
 		return
 	}
 
@@ -166,21 +185,37 @@ func (r *FirewallIPSetCIDRResource) Update(ctx context.Context, req resource.Upd
 		return
 	}
 
+	ctx, cancel := withTimeout(ctx, config.Timeouts.Update, defaultUpdateTimeout, &resp.Diagnostics)
+	defer cancel()
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client := r.provider.client(ctx, r.typeName(), config.Auth, authToken, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
 	itemPutReq := ipset_cidr.ItemPutRequest{
-		Client:    r.client,
+		Client:    withContext(ctx, client),
 		IPSetName: config.IPSetName.ValueString(),
 		CIDR:      config.CIDR.ValueString(),
 		NoMatch:   helpers.PtrTo(pvetypes.PVEBool(config.NoMatch.ValueBool())),
 		Comment:   helpers.PtrTo(config.Comment.ValueString()),
 	}
-	err := itemPutReq.Put()
+	err := mutateFirewall(ctx, client, clusterFirewall, clusterFirewall+"/ipset/"+config.IPSetName.ValueString(), func(digest string) error {
+		itemPutReq.Digest = digestParam(digest)
+		return itemPutReq.Put()
+	})
 	if err != nil {
-		resp.Diagnostics.AddError("Error updating "+r.typeName(), err.Error())
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error updating "+r.typeName(), err, firewallIPSetCIDRAPIParams)
 		return
 	}
 
 	state.Comment = config.Comment
 	state.NoMatch = config.NoMatch
+	state.Auth = config.Auth
+	state.Timeouts = config.Timeouts
 	resp.Diagnostics.Append(resp.State.Set(ctx, state)...)
 }
 
@@ -191,9 +226,27 @@ func (r *FirewallIPSetCIDRResource) Delete(ctx context.Context, req resource.Del
 		return
 	}
 
-	err := ipset_cidr.ItemDeleteRequest{Client: r.client, IPSetName: data.IPSetName.ValueString(), CIDR: data.CIDR.ValueString()}.Delete()
+	ctx, cancel := withTimeout(ctx, data.Timeouts.Delete, defaultDeleteTimeout, &resp.Diagnostics)
+	defer cancel()
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client := r.provider.client(ctx, r.typeName(), data.Auth, authToken, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := mutateFirewall(ctx, client, clusterFirewall, clusterFirewall+"/ipset/"+data.IPSetName.ValueString(), func(digest string) error {
+		// ItemDeleteRequest has no Digest.
+		deleteReq := ipset_cidr.ItemDeleteRequest{Client: withContext(ctx, client), IPSetName: data.IPSetName.ValueString(), CIDR: data.CIDR.ValueString()}
+		return deleteReq.Client.DeleteItem(deleteReq, clusterFirewall+"/ipset/"+deleteReq.IPSetName, deleteReq.CIDR, digest)
+	})
 	if err != nil {
-		resp.Diagnostics.AddError("Error deleting "+r.typeName(), err.Error())
+		if isNotFound(err, data.IPSetName.ValueString(), data.CIDR.ValueString()) {
+			return
+		}
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error deleting "+r.typeName(), err, nil)
 		return
 	}
 }
@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"testing"
 
+	proxmox "github.com/c10l/proxmoxve-client-go/api"
+	"github.com/c10l/proxmoxve-client-go/api/cluster/firewall/ipset"
+	"github.com/c10l/proxmoxve-client-go/api/cluster/firewall/ipset/ipset_cidr"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
 	"github.com/stretchr/testify/assert"
 )
@@ -49,6 +52,28 @@ func TestFirewallIPSetCIDRResource(t *testing.T) {
 					resource.TestCheckResourceAttr("proxmoxve_firewall_ipset_cidr.ten_network", "comment", "open sesame"),
 				),
 			},
+			// CIDR deleted outside of Terraform and re-created
+			{
+				PreConfig: testAccDeleteOutOfBand(t, func(c *proxmox.Client) error {
+					return ipset_cidr.ItemDeleteRequest{Client: c, IPSetName: "proxmoxve_firewall_ipset_test", CIDR: "10.0.0.0/8"}.Delete()
+				}),
+				Config: testAccFirewallIPSetCIDRResourceConfig("false"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("proxmoxve_firewall_ipset_cidr.ten_network", "cidr", "10.0.0.0/8"),
+					resource.TestCheckResourceAttr("proxmoxve_firewall_ipset_cidr.ten_network", "comment", "open sesame"),
+				),
+			},
+			// Parent IPSet deleted outside of Terraform and everything re-created
+			{
+				PreConfig: testAccDeleteOutOfBand(t, func(c *proxmox.Client) error {
+					return ipset.ItemDeleteRequest{Client: c, Name: "proxmoxve_firewall_ipset_test"}.ForceDelete()
+				}),
+				Config: testAccFirewallIPSetCIDRResourceConfig("false"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("proxmoxve_firewall_ipset_cidr.ten_network", "cidr", "10.0.0.0/8"),
+					resource.TestCheckResourceAttr("proxmoxve_firewall_ipset_cidr.ipv6_ula", "cidr", "fd65::/16"),
+				),
+			},
 			// Delete testing automatically occurs in TestCase
 		},
 	})
@@ -75,3 +100,45 @@ func testAccFirewallIPSetCIDRResourceConfig(v6noMatch string) string {
 		}
 	`, v6noMatch)
 }
+
+func TestFirewallIPSetCIDRResourceParallel(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			// Many CIDRs created concurrently
+			{
+				Config: testAccFirewallIPSetCIDRResourceParallelConfig("created"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("proxmoxve_firewall_ipset_cidr.many.0", "cidr", "10.1.0.0/24"),
+					resource.TestCheckResourceAttr("proxmoxve_firewall_ipset_cidr.many.29", "cidr", "10.1.29.0/24"),
+				),
+			},
+			// Many CIDRs updated concurrently
+			{
+				Config: testAccFirewallIPSetCIDRResourceParallelConfig("updated"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("proxmoxve_firewall_ipset_cidr.many.0", "comment", "updated"),
+					resource.TestCheckResourceAttr("proxmoxve_firewall_ipset_cidr.many.29", "comment", "updated"),
+				),
+			},
+		},
+	})
+}
+
+func testAccFirewallIPSetCIDRResourceParallelConfig(comment string) string {
+	return fmt.Sprintf(`
+		resource "proxmoxve_firewall_ipset" "test" {
+			name = "proxmoxve_firewall_ipset_parallel"
+		}
+
+		resource "proxmoxve_firewall_ipset_cidr" "many" {
+			count = 30
+
+			ipset_name = proxmoxve_firewall_ipset.test.name
+			cidr       = "10.1.${count.index}.0/24"
+			comment    = %q
+			no_match   = false
+		}
+	`, comment)
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"testing"
 
+	proxmox "github.com/c10l/proxmoxve-client-go/api"
+	"github.com/c10l/proxmoxve-client-go/api/cluster/firewall/ipset"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
 )
 
@@ -44,6 +46,17 @@ func TestFirewallIPSetResource(t *testing.T) {
 					resource.TestCheckResourceAttr("proxmoxve_firewall_ipset.test", "comment", "no comments"),
 				),
 			},
+			// Deleted outside of Terraform and re-created
+			{
+				PreConfig: testAccDeleteOutOfBand(t, func(c *proxmox.Client) error {
+					return ipset.ItemDeleteRequest{Client: c, Name: "pmve_firewall_ipset_test_renamed"}.Delete()
+				}),
+				Config: testAccFirewallIPSetResourceConfig("pmve_firewall_ipset_test_renamed", "no comments"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("proxmoxve_firewall_ipset.test", "name", "pmve_firewall_ipset_test_renamed"),
+					resource.TestCheckResourceAttr("proxmoxve_firewall_ipset.test", "comment", "no comments"),
+				),
+			},
 			// Delete testing automatically occurs in TestCase
 		},
 	})
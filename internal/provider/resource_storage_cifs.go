@@ -0,0 +1,437 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/c10l/proxmoxve-client-go/api/storage"
+	"github.com/c10l/proxmoxve-client-go/helpers"
+	pvetypes "github.com/c10l/proxmoxve-client-go/helpers/types"
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces
+var _ resource.Resource = &StorageCIFSResource{}
+var _ resource.ResourceWithImportState = &StorageCIFSResource{}
+var _ resource.ResourceWithModifyPlan = &StorageCIFSResource{}
+
+func NewStorageCIFSResource() resource.Resource {
+	return &StorageCIFSResource{}
+}
+
+// StorageCIFSResource defines the resource implementation.
+type StorageCIFSResource struct {
+	provider *providerData
+}
+
+// StorageCIFSResource describes the resource data model.
+type StorageCIFSResourceModel struct {
+	ID types.String `tfsdk:"id"`
+
+	// Required attributes
+	Name   types.String `tfsdk:"name"`
+	Server types.String `tfsdk:"server"`
+	Share  types.String `tfsdk:"share"`
+
+	// Optional attributes
+	Username      types.String `tfsdk:"username"`
+	Password      types.String `tfsdk:"password"`
+	Domain        types.String `tfsdk:"domain"`
+	SMBVersion    types.String `tfsdk:"smbversion"`
+	Subdir        types.String `tfsdk:"subdir"`
+	MountOptions  types.String `tfsdk:"mount_options"`
+	Preallocation types.String `tfsdk:"preallocation"`
+	Content       types.Set    `tfsdk:"content"`
+	Nodes         types.Set    `tfsdk:"nodes"`
+	Disable       types.Bool   `tfsdk:"disable"`
+
+	// Computed attributes
+	Type         types.String `tfsdk:"type"`
+	PruneBackups types.String `tfsdk:"prune_backups"`
+
+	Auth     types.String   `tfsdk:"auth"`
+	Timeouts timeouts.Value `tfsdk:"timeouts"`
+}
+
+// storageCIFSAPIParams maps the API parameters whose name differs from their attribute.
+var storageCIFSAPIParams = map[string]string{"storage": "name", "options": "mount_options"}
+
+func (r *StorageCIFSResource) typeName() string { return "storage_cifs" }
+
+func (r *StorageCIFSResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_" + r.typeName()
+}
+
+func (r *StorageCIFSResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Storage on an SMB/CIFS share, mounted by every node.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+			},
+			"name": schema.StringAttribute{
+				Required:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"server": schema.StringAttribute{
+				Required:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.RequiresReplace()},
+				MarkdownDescription: "Name or IP address of the SMB server.",
+			},
+			"share": schema.StringAttribute{
+				Required:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.RequiresReplace()},
+				MarkdownDescription: "Name of the share.",
+			},
+			"username": schema.StringAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "User to authenticate as. Guest access is used if unset.",
+			},
+			"password": schema.StringAttribute{
+				Optional:  true,
+				Sensitive: true,
+				MarkdownDescription: "Password of `username`. Proxmox VE never returns it, so changes made outside of Terraform are not detected. " +
+					"It is only sent when changed, as told by a hash of the last one set kept in the private state of the resource. " +
+					"Like any attribute, it is stored in the Terraform state, where it is marked as sensitive.",
+			},
+			"domain": schema.StringAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "Domain of `username`.",
+			},
+			"smbversion": schema.StringAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "SMB protocol version: `default` to negotiate the highest supported, or one of `2.0`, `2.1`, `3`, `3.0` or `3.11`.",
+			},
+			"subdir": schema.StringAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "Subdirectory of the share to use.",
+			},
+			"mount_options": schema.StringAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "Additional options of `mount.cifs`.",
+			},
+			"preallocation": schema.StringAttribute{
+				Optional: true,
+				Computed: true,
+			},
+			"content": schema.SetAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Computed:    true,
+			},
+			"nodes": schema.SetAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Computed:    true,
+			},
+			"disable": schema.BoolAttribute{
+				Optional: true,
+				Computed: true,
+			},
+			"type": schema.StringAttribute{
+				Computed: true,
+			},
+			"prune_backups": schema.StringAttribute{
+				Computed: true,
+			},
+			"auth": authAttribute(authToken),
+		},
+		Blocks: map[string]schema.Block{
+			"timeouts": timeoutsBlock(ctx),
+		},
+	}
+}
+
+func (r *StorageCIFSResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	r.provider = configureProviderData(req.ProviderData, &resp.Diagnostics)
+}
+
+func (r *StorageCIFSResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	r.provider.checkPrivileges(ctx, r.typeName(), authToken, storagePrivileges, req, resp)
+}
+
+func (r *StorageCIFSResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data *StorageCIFSResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := withTimeout(ctx, data.Timeouts.Create, defaultCreateTimeout, &resp.Diagnostics)
+	defer cancel()
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client := r.provider.client(ctx, r.typeName(), data.Auth, authToken, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	postReq := storagePostRequest{
+		PostRequest: storage.PostRequest{
+			Client:      withContext(ctx, client),
+			Storage:     data.Name.ValueString(),
+			StorageType: storage.TypeCIFS,
+		},
+		Params: r.params(data),
+	}
+	postReq.Params.Set("server", data.Server.ValueString())
+	postReq.Params.Set("share", data.Share.ValueString())
+	if !data.Password.IsNull() {
+		postReq.Params.Set("password", data.Password.ValueString())
+	}
+	if !data.Content.IsNull() {
+		if postReq.Content == nil {
+			postReq.Content = &[]string{}
+		}
+		resp.Diagnostics.Append(data.Content.ElementsAs(ctx, postReq.Content, false)...)
+	}
+	if !data.Nodes.IsNull() {
+		if postReq.Nodes == nil {
+			postReq.Nodes = &[]string{}
+		}
+		resp.Diagnostics.Append(data.Nodes.ElementsAs(ctx, postReq.Nodes, false)...)
+	}
+	if !data.Disable.IsNull() {
+		postReq.Disable = helpers.PtrTo(pvetypes.PVEBool(data.Disable.ValueBool()))
+	}
+	if !data.MountOptions.IsNull() {
+		postReq.NFSMountOptions = helpers.PtrTo(data.MountOptions.ValueString())
+	}
+	if !data.Preallocation.IsNull() {
+		postReq.Preallocation = helpers.PtrTo(data.Preallocation.ValueString())
+	}
+	_, err := postReq.Post()
+	if err != nil {
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error creating "+r.typeName(), err, storageCIFSAPIParams)
+		return
+	}
+	resp.Diagnostics.Append(keepStorageSecretHashes(ctx, resp.Private, r.secrets(data))...)
+
+	item, options, err := readStorageOptions(ctx, client, data.Name.ValueString())
+	if err != nil {
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error reading "+r.typeName(), err, nil)
+		return
+	}
+
+	resp.Diagnostics.Append(r.convertAPIGetResponseToTerraform(ctx, *item, options, data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	tflog.Trace(ctx, "created "+r.typeName())
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, data)...)
+}
+
+func (r *StorageCIFSResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data *StorageCIFSResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := withTimeout(ctx, data.Timeouts.Read, defaultReadTimeout, &resp.Diagnostics)
+	defer cancel()
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client := r.provider.client(ctx, r.typeName(), data.Auth, authToken, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	item, options, err := readStorageOptions(ctx, client, data.Name.ValueString())
+	if err != nil {
+		// If resource has been deleted outside of Terraform, we remove it from the plan state so it can be re-created.
+		if isNotFound(err, data.Name.ValueString()) {
+			resp.State.RemoveResource(ctx)
+			return
+		}
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error reading "+r.typeName(), err, nil)
+		return
+	}
+
+	if item.Type != storage.TypeCIFS {
+		resp.Diagnostics.AddError("Wrong storage type", fmt.Sprintf("Storage %s is of type %s but is declared as "+r.typeName(), data.Name.ValueString(), item.Type))
+		return
+	}
+
+	resp.Diagnostics.Append(r.convertAPIGetResponseToTerraform(ctx, *item, options, data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, data)...)
+}
+
+func (r *StorageCIFSResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data *StorageCIFSResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := withTimeout(ctx, data.Timeouts.Update, defaultUpdateTimeout, &resp.Diagnostics)
+	defer cancel()
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client := r.provider.client(ctx, r.typeName(), data.Auth, authToken, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	putReq := storagePutRequest{
+		ItemPutRequest: storage.ItemPutRequest{Client: withContext(ctx, client), Storage: data.Name.ValueString()},
+		Params:         r.params(data),
+	}
+	setChangedStorageSecrets(ctx, req.Private, putReq.Params, r.secrets(data), &resp.Diagnostics)
+	if !data.Content.IsNull() {
+		if putReq.Content == nil {
+			putReq.Content = &[]string{}
+		}
+		resp.Diagnostics.Append(data.Content.ElementsAs(ctx, putReq.Content, false)...)
+	}
+	if !data.Nodes.IsNull() {
+		if putReq.Nodes == nil {
+			putReq.Nodes = &[]string{}
+		}
+		resp.Diagnostics.Append(data.Nodes.ElementsAs(ctx, putReq.Nodes, false)...)
+	}
+	if !data.Disable.IsNull() {
+		putReq.Disable = helpers.PtrTo(pvetypes.PVEBool(data.Disable.ValueBool()))
+	}
+	if !data.MountOptions.IsNull() {
+		putReq.NFSMountOptions = helpers.PtrTo(data.MountOptions.ValueString())
+	}
+	if !data.Preallocation.IsNull() {
+		putReq.Preallocation = helpers.PtrTo(data.Preallocation.ValueString())
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	_, err := putReq.Put()
+	if err != nil {
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error updating "+r.typeName(), err, storageCIFSAPIParams)
+		return
+	}
+	resp.Diagnostics.Append(keepStorageSecretHashes(ctx, resp.Private, r.secrets(data))...)
+
+	item, options, err := readStorageOptions(ctx, client, data.Name.ValueString())
+	if err != nil {
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error reading "+r.typeName(), err, nil)
+		return
+	}
+
+	resp.Diagnostics.Append(r.convertAPIGetResponseToTerraform(ctx, *item, options, data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	tflog.Trace(ctx, "updated "+r.typeName())
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, data)...)
+}
+
+func (r *StorageCIFSResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data *StorageCIFSResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := withTimeout(ctx, data.Timeouts.Delete, defaultDeleteTimeout, &resp.Diagnostics)
+	defer cancel()
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client := r.provider.client(ctx, r.typeName(), data.Auth, authToken, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := storage.ItemDeleteRequest{Client: withContext(ctx, client), Storage: data.Name.ValueString()}.Delete()
+	if err != nil {
+		if isNotFound(err, data.Name.ValueString()) {
+			return
+		}
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error deleting "+r.typeName(), err, nil)
+		return
+	}
+}
+
+func (r *StorageCIFSResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resource.ImportStatePassthroughID(ctx, path.Root("name"), req, resp)
+	// The password in use is not known: the one configured is sent by the next
+	// update.
+	resp.Diagnostics.Append(keepStorageSecretHashes(ctx, resp.Private, map[string]types.String{"password": types.StringNull()})...)
+}
+
+// params returns the CIFS options which can be both created and updated, and
+// which storage.PostRequest and storage.ItemPutRequest lack. The password is
+// left out: it is only sent when changed.
+func (r *StorageCIFSResource) params(data *StorageCIFSResourceModel) url.Values {
+	params := url.Values{}
+	for name, value := range map[string]types.String{
+		"username":   data.Username,
+		"domain":     data.Domain,
+		"smbversion": data.SMBVersion,
+		"subdir":     data.Subdir,
+	} {
+		if !value.IsNull() {
+			params.Set(name, value.ValueString())
+		}
+	}
+	return params
+}
+
+// secrets returns the options never returned by the API, by parameter.
+func (r *StorageCIFSResource) secrets(data *StorageCIFSResourceModel) map[string]types.String {
+	return map[string]types.String{"password": data.Password}
+}
+
+// convertAPIGetResponseToTerraform sets the attributes of tfData read from the
+// API, which never returns the password: it is left as configured.
+func (r *StorageCIFSResource) convertAPIGetResponseToTerraform(ctx context.Context, apiData storage.ItemGetResponse, options storageOptions, tfData *StorageCIFSResourceModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+	diags = append(diags, tfsdk.ValueFrom(ctx, apiData.Content, types.SetType{ElemType: types.StringType}, &tfData.Content)...)
+	diags = append(diags, tfsdk.ValueFrom(ctx, apiData.Nodes, types.SetType{ElemType: types.StringType}, &tfData.Nodes)...)
+
+	tfData.ID = types.StringValue(apiData.Storage)
+	tfData.Name = types.StringValue(apiData.Storage)
+	tfData.Type = types.StringValue(apiData.Type)
+	tfData.PruneBackups = types.StringValue(apiData.PruneBackups)
+	tfData.Server = types.StringValue(options.string("server"))
+	tfData.Share = types.StringValue(options.string("share"))
+	tfData.Username = types.StringValue(options.string("username"))
+	tfData.Domain = types.StringValue(options.string("domain"))
+	tfData.SMBVersion = types.StringValue(options.string("smbversion"))
+	tfData.Subdir = types.StringValue(options.string("subdir"))
+	tfData.MountOptions = types.StringValue(apiData.NFSMountOptions)
+
+	tfData.Disable = types.BoolValue(apiData.Disable)
+	tfData.Preallocation = types.StringValue(apiData.Preallocation)
+
+	return diags
+}
@@ -0,0 +1,86 @@
+package provider
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+func TestAccStorageCIFSResource(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			// Create and Read testing
+			{
+				Config: testAccStorageCIFSResourceConfig("secret", "3"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("proxmoxve_storage_cifs.test", "id", "testacc_storage_cifs"),
+					resource.TestCheckResourceAttr("proxmoxve_storage_cifs.test", "server", "1.2.3.4"),
+					resource.TestCheckResourceAttr("proxmoxve_storage_cifs.test", "share", "backups"),
+					resource.TestCheckResourceAttr("proxmoxve_storage_cifs.test", "username", "pve"),
+					resource.TestCheckResourceAttr("proxmoxve_storage_cifs.test", "password", "secret"),
+					resource.TestCheckResourceAttr("proxmoxve_storage_cifs.test", "domain", "EXAMPLE"),
+					resource.TestCheckResourceAttr("proxmoxve_storage_cifs.test", "smbversion", "3"),
+					resource.TestCheckResourceAttr("proxmoxve_storage_cifs.test", "subdir", ""),
+					resource.TestCheckResourceAttr("proxmoxve_storage_cifs.test", "mount_options", ""),
+					resource.TestCheckResourceAttr("proxmoxve_storage_cifs.test", "disable", "true"),
+					resource.TestCheckResourceAttr("proxmoxve_storage_cifs.test", "type", "cifs"),
+					resource.TestCheckTypeSetElemAttr("proxmoxve_storage_cifs.test", "content.*", "backup"),
+					testAccCheckStorageSecret("testacc_storage_cifs", "password", "secret"),
+				),
+			},
+			// ImportState testing
+			{
+				ResourceName:      "proxmoxve_storage_cifs.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+				// The password is never returned by the API.
+				ImportStateVerifyIgnore: []string{"password"},
+			},
+			// Update and Read testing
+			{
+				Config: testAccStorageCIFSResourceConfig("changed", "default"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("proxmoxve_storage_cifs.test", "password", "changed"),
+					resource.TestCheckResourceAttr("proxmoxve_storage_cifs.test", "smbversion", "default"),
+					testAccCheckStorageSecret("testacc_storage_cifs", "password", "changed"),
+				),
+			},
+			// Delete testing automatically occurs in TestCase
+		},
+	})
+}
+
+// testAccCheckStorageSecret checks that the secret option of the storage name
+// was last set to want on the mock server, as the API never returns it. Nothing
+// is checked against a real server.
+func testAccCheckStorageSecret(name, option, want string) resource.TestCheckFunc {
+	return func(*terraform.State) error {
+		if testAccMockServer == nil {
+			return nil
+		}
+		if got := testAccMockServer.StorageSecret(name, option); got != want {
+			return fmt.Errorf("%s of storage %s is %q, want %q", option, name, got, want)
+		}
+		return nil
+	}
+}
+
+func testAccStorageCIFSResourceConfig(password, smbversion string) string {
+	return fmt.Sprintf(`
+		resource "proxmoxve_storage_cifs" "test" {
+			name       = "testacc_storage_cifs"
+			server     = "1.2.3.4"
+			share      = "backups"
+			username   = "pve"
+			password   = %q
+			domain     = "EXAMPLE"
+			smbversion = %q
+			content    = ["backup"]
+			disable    = true
+		}
+		`, password, smbversion)
+}
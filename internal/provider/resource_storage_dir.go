@@ -3,12 +3,11 @@ package provider
 import (
 	"context"
 	"fmt"
-	"strings"
 
-	"github.com/c10l/proxmoxve-client-go/api"
 	"github.com/c10l/proxmoxve-client-go/api/storage"
 	"github.com/c10l/proxmoxve-client-go/helpers"
 	pvetypes "github.com/c10l/proxmoxve-client-go/helpers/types"
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
 	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
@@ -21,6 +20,7 @@ import (
 // Ensure provider defined types fully satisfy framework interfaces
 var _ resource.Resource = &StorageDirResource{}
 var _ resource.ResourceWithImportState = &StorageDirResource{}
+var _ resource.ResourceWithModifyPlan = &StorageDirResource{}
 
 func NewStorageDirResource() resource.Resource {
 	return &StorageDirResource{}
@@ -28,7 +28,7 @@ func NewStorageDirResource() resource.Resource {
 
 // StorageDirResource defines the resource implementation.
 type StorageDirResource struct {
-	client *api.Client
+	provider *providerData
 }
 
 // StorageDirResource describes the resource data model.
@@ -49,8 +49,14 @@ type StorageDirResourceModel struct {
 	// Computed attributes
 	Type         types.String `tfsdk:"type"`
 	PruneBackups types.String `tfsdk:"prune_backups"`
+
+	Auth     types.String   `tfsdk:"auth"`
+	Timeouts timeouts.Value `tfsdk:"timeouts"`
 }
 
+// storageDirAPIParams maps the API parameters whose name differs from their attribute.
+var storageDirAPIParams = map[string]string{"storage": "name"}
+
 func (r *StorageDirResource) typeName() string { return "storage_dir" }
 
 func (r *StorageDirResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
@@ -97,46 +103,41 @@ func (r *StorageDirResource) Schema(ctx context.Context, req resource.SchemaRequ
 			"prune_backups": schema.StringAttribute{
 				Computed: true,
 			},
+			"auth": authAttribute(authToken),
+		},
+		Blocks: map[string]schema.Block{
+			"timeouts": timeoutsBlock(ctx),
 		},
 	}
 }
 
 func (r *StorageDirResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
-	// Prevent panic if the provider has not been configured.
-	if req.ProviderData == nil {
-		return
-	}
-
-	clientFunc, ok := req.ProviderData.(map[string]getClientFunc)["token"]
+	r.provider = configureProviderData(req.ProviderData, &resp.Diagnostics)
+}
 
-	if !ok {
-		resp.Diagnostics.AddError(
-			"Unexpected Data Source Configure Type",
-			fmt.Sprintf("Expected *proxmox.Client, got: %T. Please report this issue to the provider developers.", req.ProviderData),
-		)
+func (r *StorageDirResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	r.provider.checkPrivileges(ctx, r.typeName(), authToken, storagePrivileges, req, resp)
+}
 
+func (r *StorageDirResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data *StorageDirResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	client, err := clientFunc()
-	if err != nil {
-		resp.Diagnostics.AddError(
-			"Unable to instantiate client",
-			err.Error(),
-		)
+	ctx, cancel := withTimeout(ctx, data.Timeouts.Create, defaultCreateTimeout, &resp.Diagnostics)
+	defer cancel()
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
-	r.client = client
-}
-
-func (r *StorageDirResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
-	var data *StorageDirResourceModel
-	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	client := r.provider.client(ctx, r.typeName(), data.Auth, authToken, &resp.Diagnostics)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	postReq := storage.PostRequest{Client: r.client, Storage: data.Name.ValueString(), StorageType: storage.TypeDir, DirPath: helpers.PtrTo(data.Path.ValueString())}
+	postReq := storage.PostRequest{Client: withContext(ctx, client), Storage: data.Name.ValueString(), StorageType: storage.TypeDir, DirPath: helpers.PtrTo(data.Path.ValueString())}
 	if !data.Content.IsNull() {
 		if postReq.Content == nil {
 			postReq.Content = &[]string{}
@@ -160,13 +161,13 @@ func (r *StorageDirResource) Create(ctx context.Context, req resource.CreateRequ
 	}
 	_, err := postReq.Post()
 	if err != nil {
-		resp.Diagnostics.AddError("Error creating "+r.typeName(), err.Error())
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error creating "+r.typeName(), err, storageDirAPIParams)
 		return
 	}
 
-	storage, err := storage.ItemGetRequest{Client: r.client, Storage: data.Name.ValueString()}.Get()
+	storage, err := storage.ItemGetRequest{Client: withContext(ctx, client), Storage: data.Name.ValueString()}.Get()
 	if err != nil {
-		resp.Diagnostics.AddError("Error reading "+r.typeName(), err.Error())
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error reading "+r.typeName(), err, nil)
 		return
 	}
 
@@ -187,14 +188,25 @@ func (r *StorageDirResource) Read(ctx context.Context, req resource.ReadRequest,
 		return
 	}
 
-	item, err := storage.ItemGetRequest{Client: r.client, Storage: data.Name.ValueString()}.Get()
+	ctx, cancel := withTimeout(ctx, data.Timeouts.Read, defaultReadTimeout, &resp.Diagnostics)
+	defer cancel()
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client := r.provider.client(ctx, r.typeName(), data.Auth, authToken, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	item, err := readStorage(ctx, client, data.Name.ValueString())
 	if err != nil {
 		// If resource has been deleted outside of Terraform, we remove it from the plan state so it can be re-created.
-		if strings.Contains(err.Error(), fmt.Sprintf("500 storage '%s' does not exist", data.Name.ValueString())) {
+		if isNotFound(err, data.Name.ValueString()) {
 			resp.State.RemoveResource(ctx)
 			return
 		}
-		resp.Diagnostics.AddError("Error reading "+r.typeName(), err.Error())
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error reading "+r.typeName(), err, nil)
 		return
 	}
 
@@ -218,7 +230,18 @@ func (r *StorageDirResource) Update(ctx context.Context, req resource.UpdateRequ
 		return
 	}
 
-	putReq := storage.ItemPutRequest{Client: r.client, Storage: data.Name.ValueString()}
+	ctx, cancel := withTimeout(ctx, data.Timeouts.Update, defaultUpdateTimeout, &resp.Diagnostics)
+	defer cancel()
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client := r.provider.client(ctx, r.typeName(), data.Auth, authToken, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	putReq := storage.ItemPutRequest{Client: withContext(ctx, client), Storage: data.Name.ValueString()}
 	if !data.Content.IsNull() {
 		if putReq.Content == nil {
 			putReq.Content = &[]string{}
@@ -242,13 +265,13 @@ func (r *StorageDirResource) Update(ctx context.Context, req resource.UpdateRequ
 	}
 	_, err := putReq.Put()
 	if err != nil {
-		resp.Diagnostics.AddError("Error creating "+r.typeName(), err.Error())
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error updating "+r.typeName(), err, storageDirAPIParams)
 		return
 	}
 
-	storage, err := storage.ItemGetRequest{Client: r.client, Storage: data.Name.ValueString()}.Get()
+	storage, err := storage.ItemGetRequest{Client: withContext(ctx, client), Storage: data.Name.ValueString()}.Get()
 	if err != nil {
-		resp.Diagnostics.AddError("Error reading "+r.typeName(), err.Error())
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error reading "+r.typeName(), err, nil)
 		return
 	}
 
@@ -270,9 +293,23 @@ func (r *StorageDirResource) Delete(ctx context.Context, req resource.DeleteRequ
 		return
 	}
 
-	err := storage.ItemDeleteRequest{Client: r.client, Storage: data.Name.ValueString()}.Delete()
+	ctx, cancel := withTimeout(ctx, data.Timeouts.Delete, defaultDeleteTimeout, &resp.Diagnostics)
+	defer cancel()
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client := r.provider.client(ctx, r.typeName(), data.Auth, authToken, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := storage.ItemDeleteRequest{Client: withContext(ctx, client), Storage: data.Name.ValueString()}.Delete()
 	if err != nil {
-		resp.Diagnostics.AddError("Error deleting "+r.typeName(), err.Error())
+		if isNotFound(err, data.Name.ValueString()) {
+			return
+		}
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error deleting "+r.typeName(), err, nil)
 		return
 	}
 }
@@ -0,0 +1,390 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/c10l/proxmoxve-client-go/api/storage"
+	"github.com/c10l/proxmoxve-client-go/helpers"
+	pvetypes "github.com/c10l/proxmoxve-client-go/helpers/types"
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces
+var _ resource.Resource = &StorageLVMResource{}
+var _ resource.ResourceWithImportState = &StorageLVMResource{}
+var _ resource.ResourceWithModifyPlan = &StorageLVMResource{}
+
+func NewStorageLVMResource() resource.Resource {
+	return &StorageLVMResource{}
+}
+
+// StorageLVMResource defines the resource implementation.
+type StorageLVMResource struct {
+	provider *providerData
+}
+
+// StorageLVMResource describes the resource data model.
+type StorageLVMResourceModel struct {
+	ID types.String `tfsdk:"id"`
+
+	// Required attributes
+	Name   types.String `tfsdk:"name"`
+	VGName types.String `tfsdk:"vgname"`
+
+	// Optional attributes
+	Base                 types.String `tfsdk:"base"`
+	Content              types.Set    `tfsdk:"content"`
+	Nodes                types.Set    `tfsdk:"nodes"`
+	Disable              types.Bool   `tfsdk:"disable"`
+	Shared               types.Bool   `tfsdk:"shared"`
+	SafeRemove           types.Bool   `tfsdk:"saferemove"`
+	SafeRemoveThroughput types.String `tfsdk:"saferemove_throughput"`
+	TaggedOnly           types.Bool   `tfsdk:"tagged_only"`
+
+	// Computed attributes
+	Type types.String `tfsdk:"type"`
+
+	Auth     types.String   `tfsdk:"auth"`
+	Timeouts timeouts.Value `tfsdk:"timeouts"`
+}
+
+// storageLVMAPIParams maps the API parameters whose name differs from their attribute.
+var storageLVMAPIParams = map[string]string{"storage": "name"}
+
+func (r *StorageLVMResource) typeName() string { return "storage_lvm" }
+
+func (r *StorageLVMResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_" + r.typeName()
+}
+
+func (r *StorageLVMResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Storage backed by an LVM volume group, with a logical volume per disk image.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+			},
+			"name": schema.StringAttribute{
+				Required:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"vgname": schema.StringAttribute{
+				Required:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.RequiresReplace()},
+				MarkdownDescription: "Name of the volume group.",
+			},
+			"base": schema.StringAttribute{
+				Optional:            true,
+				Computed:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.UseStateForUnknown(), stringplanmodifier.RequiresReplace()},
+				MarkdownDescription: "Volume the volume group is created on, e.g. an iSCSI LUN such as `iscsi-storage:0.0.1.scsi-...`.",
+			},
+			"content": schema.SetAttribute{
+				ElementType:         types.StringType,
+				Optional:            true,
+				Computed:            true,
+				Validators:          []validator.Set{storageContentValidator{storage.TypeLVM, []string{storage.ContentImages, storage.ContentRootDir}}},
+				MarkdownDescription: "Content types stored: `images` and/or `rootdir`.",
+			},
+			"nodes": schema.SetAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Computed:    true,
+			},
+			"disable": schema.BoolAttribute{
+				Optional: true,
+				Computed: true,
+			},
+			"shared": schema.BoolAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "Whether the volume group is on storage shared by all the nodes, e.g. an iSCSI LUN.",
+			},
+			"saferemove": schema.BoolAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "Whether to zero out the data of removed volumes.",
+			},
+			"saferemove_throughput": schema.StringAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "Throughput limit of zeroing out removed volumes, passed to `cstream -t`, e.g. `-10485760` for 10 MiB/s.",
+			},
+			"tagged_only": schema.BoolAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "Whether to only use the logical volumes tagged with `pve-vm-ID`.",
+			},
+			"type": schema.StringAttribute{
+				Computed: true,
+			},
+			"auth": authAttribute(authToken),
+		},
+		Blocks: map[string]schema.Block{
+			"timeouts": timeoutsBlock(ctx),
+		},
+	}
+}
+
+func (r *StorageLVMResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	r.provider = configureProviderData(req.ProviderData, &resp.Diagnostics)
+}
+
+func (r *StorageLVMResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	r.provider.checkPrivileges(ctx, r.typeName(), authToken, storagePrivileges, req, resp)
+}
+
+func (r *StorageLVMResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data *StorageLVMResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := withTimeout(ctx, data.Timeouts.Create, defaultCreateTimeout, &resp.Diagnostics)
+	defer cancel()
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client := r.provider.client(ctx, r.typeName(), data.Auth, authToken, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	postReq := storagePostRequest{
+		PostRequest: storage.PostRequest{
+			Client:      withContext(ctx, client),
+			Storage:     data.Name.ValueString(),
+			StorageType: storage.TypeLVM,
+		},
+		Params: r.params(data),
+	}
+	postReq.Params.Set("vgname", data.VGName.ValueString())
+	if !data.Base.IsNull() {
+		postReq.Params.Set("base", data.Base.ValueString())
+	}
+	if !data.Content.IsNull() {
+		if postReq.Content == nil {
+			postReq.Content = &[]string{}
+		}
+		resp.Diagnostics.Append(data.Content.ElementsAs(ctx, postReq.Content, false)...)
+	}
+	if !data.Nodes.IsNull() {
+		if postReq.Nodes == nil {
+			postReq.Nodes = &[]string{}
+		}
+		resp.Diagnostics.Append(data.Nodes.ElementsAs(ctx, postReq.Nodes, false)...)
+	}
+	if !data.Disable.IsNull() {
+		postReq.Disable = helpers.PtrTo(pvetypes.PVEBool(data.Disable.ValueBool()))
+	}
+	if !data.Shared.IsNull() {
+		postReq.DirShared = helpers.PtrTo(pvetypes.PVEBool(data.Shared.ValueBool()))
+	}
+	_, err := postReq.Post()
+	if err != nil {
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error creating "+r.typeName(), err, storageLVMAPIParams)
+		return
+	}
+
+	item, options, err := readStorageOptions(ctx, client, data.Name.ValueString())
+	if err != nil {
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error reading "+r.typeName(), err, nil)
+		return
+	}
+
+	resp.Diagnostics.Append(r.convertAPIGetResponseToTerraform(ctx, *item, options, data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	tflog.Trace(ctx, "created "+r.typeName())
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, data)...)
+}
+
+func (r *StorageLVMResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data *StorageLVMResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := withTimeout(ctx, data.Timeouts.Read, defaultReadTimeout, &resp.Diagnostics)
+	defer cancel()
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client := r.provider.client(ctx, r.typeName(), data.Auth, authToken, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	item, options, err := readStorageOptions(ctx, client, data.Name.ValueString())
+	if err != nil {
+		// If resource has been deleted outside of Terraform, we remove it from the plan state so it can be re-created.
+		if isNotFound(err, data.Name.ValueString()) {
+			resp.State.RemoveResource(ctx)
+			return
+		}
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error reading "+r.typeName(), err, nil)
+		return
+	}
+
+	if item.Type != storage.TypeLVM {
+		resp.Diagnostics.AddError("Wrong storage type", fmt.Sprintf("Storage %s is of type %s but is declared as "+r.typeName(), data.Name.ValueString(), item.Type))
+		return
+	}
+
+	resp.Diagnostics.Append(r.convertAPIGetResponseToTerraform(ctx, *item, options, data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, data)...)
+}
+
+func (r *StorageLVMResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data *StorageLVMResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := withTimeout(ctx, data.Timeouts.Update, defaultUpdateTimeout, &resp.Diagnostics)
+	defer cancel()
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client := r.provider.client(ctx, r.typeName(), data.Auth, authToken, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	putReq := storagePutRequest{
+		ItemPutRequest: storage.ItemPutRequest{Client: withContext(ctx, client), Storage: data.Name.ValueString()},
+		Params:         r.params(data),
+	}
+	if !data.Content.IsNull() {
+		if putReq.Content == nil {
+			putReq.Content = &[]string{}
+		}
+		resp.Diagnostics.Append(data.Content.ElementsAs(ctx, putReq.Content, false)...)
+	}
+	if !data.Nodes.IsNull() {
+		if putReq.Nodes == nil {
+			putReq.Nodes = &[]string{}
+		}
+		resp.Diagnostics.Append(data.Nodes.ElementsAs(ctx, putReq.Nodes, false)...)
+	}
+	if !data.Disable.IsNull() {
+		putReq.Disable = helpers.PtrTo(pvetypes.PVEBool(data.Disable.ValueBool()))
+	}
+	if !data.Shared.IsNull() {
+		putReq.Shared = helpers.PtrTo(pvetypes.PVEBool(data.Shared.ValueBool()))
+	}
+	_, err := putReq.Put()
+	if err != nil {
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error updating "+r.typeName(), err, storageLVMAPIParams)
+		return
+	}
+
+	item, options, err := readStorageOptions(ctx, client, data.Name.ValueString())
+	if err != nil {
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error reading "+r.typeName(), err, nil)
+		return
+	}
+
+	resp.Diagnostics.Append(r.convertAPIGetResponseToTerraform(ctx, *item, options, data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	tflog.Trace(ctx, "updated "+r.typeName())
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, data)...)
+}
+
+func (r *StorageLVMResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data *StorageLVMResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := withTimeout(ctx, data.Timeouts.Delete, defaultDeleteTimeout, &resp.Diagnostics)
+	defer cancel()
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client := r.provider.client(ctx, r.typeName(), data.Auth, authToken, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := storage.ItemDeleteRequest{Client: withContext(ctx, client), Storage: data.Name.ValueString()}.Delete()
+	if err != nil {
+		if isNotFound(err, data.Name.ValueString()) {
+			return
+		}
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error deleting "+r.typeName(), err, nil)
+		return
+	}
+}
+
+func (r *StorageLVMResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resource.ImportStatePassthroughID(ctx, path.Root("name"), req, resp)
+}
+
+// params returns the LVM options which can be both created and updated, and
+// which storage.PostRequest and storage.ItemPutRequest lack.
+func (r *StorageLVMResource) params(data *StorageLVMResourceModel) url.Values {
+	params := url.Values{}
+	if !data.SafeRemove.IsNull() {
+		params.Set("saferemove", pvetypes.PVEBool(data.SafeRemove.ValueBool()).ToAPIRequestParam())
+	}
+	if !data.SafeRemoveThroughput.IsNull() {
+		params.Set("saferemove_throughput", data.SafeRemoveThroughput.ValueString())
+	}
+	if !data.TaggedOnly.IsNull() {
+		params.Set("tagged_only", pvetypes.PVEBool(data.TaggedOnly.ValueBool()).ToAPIRequestParam())
+	}
+	return params
+}
+
+func (r *StorageLVMResource) convertAPIGetResponseToTerraform(ctx context.Context, apiData storage.ItemGetResponse, options storageOptions, tfData *StorageLVMResourceModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+	diags = append(diags, tfsdk.ValueFrom(ctx, apiData.Content, types.SetType{ElemType: types.StringType}, &tfData.Content)...)
+	diags = append(diags, tfsdk.ValueFrom(ctx, apiData.Nodes, types.SetType{ElemType: types.StringType}, &tfData.Nodes)...)
+
+	tfData.ID = types.StringValue(apiData.Storage)
+	tfData.Name = types.StringValue(apiData.Storage)
+	tfData.Type = types.StringValue(apiData.Type)
+	tfData.VGName = types.StringValue(options.string("vgname"))
+	tfData.Base = types.StringValue(options.string("base"))
+	tfData.SafeRemoveThroughput = types.StringValue(options.string("saferemove_throughput"))
+
+	tfData.Disable = types.BoolValue(apiData.Disable)
+	tfData.Shared = types.BoolValue(apiData.Shared)
+	tfData.SafeRemove = types.BoolValue(options.bool("saferemove"))
+	tfData.TaggedOnly = types.BoolValue(options.bool("tagged_only"))
+
+	return diags
+}
@@ -0,0 +1,68 @@
+package provider
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestAccStorageLVMResource(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			// Create and Read testing
+			{
+				Config: testAccStorageLVMResourceConfig(false, []string{"foobar"}),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("proxmoxve_storage_lvm.test", "id", "testacc_storage_lvm"),
+					resource.TestCheckResourceAttr("proxmoxve_storage_lvm.test", "vgname", "vg_test"),
+					resource.TestCheckResourceAttr("proxmoxve_storage_lvm.test", "base", ""),
+					resource.TestCheckResourceAttr("proxmoxve_storage_lvm.test", "disable", "false"),
+					resource.TestCheckResourceAttr("proxmoxve_storage_lvm.test", "shared", "true"),
+					resource.TestCheckResourceAttr("proxmoxve_storage_lvm.test", "saferemove", "false"),
+					resource.TestCheckResourceAttr("proxmoxve_storage_lvm.test", "saferemove_throughput", "-10485760"),
+					resource.TestCheckResourceAttr("proxmoxve_storage_lvm.test", "tagged_only", "false"),
+					resource.TestCheckResourceAttr("proxmoxve_storage_lvm.test", "type", "lvm"),
+					resource.TestCheckTypeSetElemAttr("proxmoxve_storage_lvm.test", "content.*", "images"),
+					resource.TestCheckTypeSetElemAttr("proxmoxve_storage_lvm.test", "content.*", "rootdir"),
+					resource.TestCheckTypeSetElemAttr("proxmoxve_storage_lvm.test", "nodes.*", "foobar"),
+				),
+			},
+			// ImportState testing
+			{
+				ResourceName:      "proxmoxve_storage_lvm.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+			// Update and Read testing
+			{
+				Config: testAccStorageLVMResourceConfig(true, []string{"foo", "baz", "quux"}),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckTypeSetElemAttr("proxmoxve_storage_lvm.test", "nodes.*", "foo"),
+					resource.TestCheckTypeSetElemAttr("proxmoxve_storage_lvm.test", "nodes.*", "baz"),
+					resource.TestCheckTypeSetElemAttr("proxmoxve_storage_lvm.test", "nodes.*", "quux"),
+					resource.TestCheckResourceAttr("proxmoxve_storage_lvm.test", "saferemove", "true"),
+					resource.TestCheckResourceAttr("proxmoxve_storage_lvm.test", "tagged_only", "true"),
+				),
+			},
+			// Delete testing automatically occurs in TestCase
+		},
+	})
+}
+
+func testAccStorageLVMResourceConfig(safeRemove bool, nodes []string) string {
+	return fmt.Sprintf(`
+		resource "proxmoxve_storage_lvm" "test" {
+			name                  = "testacc_storage_lvm"
+			vgname                = "vg_test"
+			shared                = true
+			saferemove            = %t
+			saferemove_throughput = "-10485760"
+			tagged_only           = %t
+			nodes                 = ["%s"]
+		}
+		`, safeRemove, safeRemove, strings.Join(nodes, `","`))
+}
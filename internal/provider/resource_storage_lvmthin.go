@@ -0,0 +1,332 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/c10l/proxmoxve-client-go/api/storage"
+	"github.com/c10l/proxmoxve-client-go/helpers"
+	pvetypes "github.com/c10l/proxmoxve-client-go/helpers/types"
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces
+var _ resource.Resource = &StorageLVMThinResource{}
+var _ resource.ResourceWithImportState = &StorageLVMThinResource{}
+var _ resource.ResourceWithModifyPlan = &StorageLVMThinResource{}
+
+func NewStorageLVMThinResource() resource.Resource {
+	return &StorageLVMThinResource{}
+}
+
+// StorageLVMThinResource defines the resource implementation.
+type StorageLVMThinResource struct {
+	provider *providerData
+}
+
+// StorageLVMThinResource describes the resource data model.
+type StorageLVMThinResourceModel struct {
+	ID types.String `tfsdk:"id"`
+
+	// Required attributes
+	Name     types.String `tfsdk:"name"`
+	VGName   types.String `tfsdk:"vgname"`
+	ThinPool types.String `tfsdk:"thinpool"`
+
+	// Optional attributes
+	Content types.Set  `tfsdk:"content"`
+	Nodes   types.Set  `tfsdk:"nodes"`
+	Disable types.Bool `tfsdk:"disable"`
+
+	// Computed attributes
+	Type types.String `tfsdk:"type"`
+
+	Auth     types.String   `tfsdk:"auth"`
+	Timeouts timeouts.Value `tfsdk:"timeouts"`
+}
+
+// storageLVMThinAPIParams maps the API parameters whose name differs from their attribute.
+var storageLVMThinAPIParams = map[string]string{"storage": "name"}
+
+func (r *StorageLVMThinResource) typeName() string { return "storage_lvmthin" }
+
+func (r *StorageLVMThinResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_" + r.typeName()
+}
+
+func (r *StorageLVMThinResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Storage backed by an LVM thin pool, with a thinly provisioned logical volume per disk image. The default `local-lvm` storage of a node installed on ext4 or XFS can be imported by its name.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+			},
+			"name": schema.StringAttribute{
+				Required:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"vgname": schema.StringAttribute{
+				Required:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.RequiresReplace()},
+				MarkdownDescription: "Name of the volume group holding the thin pool.",
+			},
+			"thinpool": schema.StringAttribute{
+				Required:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.RequiresReplace()},
+				MarkdownDescription: "Name of the thin pool in the volume group.",
+			},
+			"content": schema.SetAttribute{
+				ElementType:         types.StringType,
+				Optional:            true,
+				Computed:            true,
+				Validators:          []validator.Set{storageContentValidator{storage.TypeLVMThin, []string{storage.ContentImages, storage.ContentRootDir}}},
+				MarkdownDescription: "Content types stored: `images` and/or `rootdir`.",
+			},
+			"nodes": schema.SetAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Computed:    true,
+			},
+			"disable": schema.BoolAttribute{
+				Optional: true,
+				Computed: true,
+			},
+			"type": schema.StringAttribute{
+				Computed: true,
+			},
+			"auth": authAttribute(authToken),
+		},
+		Blocks: map[string]schema.Block{
+			"timeouts": timeoutsBlock(ctx),
+		},
+	}
+}
+
+func (r *StorageLVMThinResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	r.provider = configureProviderData(req.ProviderData, &resp.Diagnostics)
+}
+
+func (r *StorageLVMThinResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	r.provider.checkPrivileges(ctx, r.typeName(), authToken, storagePrivileges, req, resp)
+}
+
+func (r *StorageLVMThinResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data *StorageLVMThinResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := withTimeout(ctx, data.Timeouts.Create, defaultCreateTimeout, &resp.Diagnostics)
+	defer cancel()
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client := r.provider.client(ctx, r.typeName(), data.Auth, authToken, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	postReq := storagePostRequest{
+		PostRequest: storage.PostRequest{
+			Client:      withContext(ctx, client),
+			Storage:     data.Name.ValueString(),
+			StorageType: storage.TypeLVMThin,
+		},
+		Params: url.Values{"vgname": {data.VGName.ValueString()}, "thinpool": {data.ThinPool.ValueString()}},
+	}
+	if !data.Content.IsNull() {
+		if postReq.Content == nil {
+			postReq.Content = &[]string{}
+		}
+		resp.Diagnostics.Append(data.Content.ElementsAs(ctx, postReq.Content, false)...)
+	}
+	if !data.Nodes.IsNull() {
+		if postReq.Nodes == nil {
+			postReq.Nodes = &[]string{}
+		}
+		resp.Diagnostics.Append(data.Nodes.ElementsAs(ctx, postReq.Nodes, false)...)
+	}
+	if !data.Disable.IsNull() {
+		postReq.Disable = helpers.PtrTo(pvetypes.PVEBool(data.Disable.ValueBool()))
+	}
+	_, err := postReq.Post()
+	if err != nil {
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error creating "+r.typeName(), err, storageLVMThinAPIParams)
+		return
+	}
+
+	item, options, err := readStorageOptions(ctx, client, data.Name.ValueString())
+	if err != nil {
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error reading "+r.typeName(), err, nil)
+		return
+	}
+
+	resp.Diagnostics.Append(r.convertAPIGetResponseToTerraform(ctx, *item, options, data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	tflog.Trace(ctx, "created "+r.typeName())
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, data)...)
+}
+
+func (r *StorageLVMThinResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data *StorageLVMThinResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := withTimeout(ctx, data.Timeouts.Read, defaultReadTimeout, &resp.Diagnostics)
+	defer cancel()
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client := r.provider.client(ctx, r.typeName(), data.Auth, authToken, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	item, options, err := readStorageOptions(ctx, client, data.Name.ValueString())
+	if err != nil {
+		// If resource has been deleted outside of Terraform, we remove it from the plan state so it can be re-created.
+		if isNotFound(err, data.Name.ValueString()) {
+			resp.State.RemoveResource(ctx)
+			return
+		}
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error reading "+r.typeName(), err, nil)
+		return
+	}
+
+	if item.Type != storage.TypeLVMThin {
+		resp.Diagnostics.AddError("Wrong storage type", fmt.Sprintf("Storage %s is of type %s but is declared as "+r.typeName(), data.Name.ValueString(), item.Type))
+		return
+	}
+
+	resp.Diagnostics.Append(r.convertAPIGetResponseToTerraform(ctx, *item, options, data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, data)...)
+}
+
+func (r *StorageLVMThinResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data *StorageLVMThinResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := withTimeout(ctx, data.Timeouts.Update, defaultUpdateTimeout, &resp.Diagnostics)
+	defer cancel()
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client := r.provider.client(ctx, r.typeName(), data.Auth, authToken, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	putReq := storage.ItemPutRequest{Client: withContext(ctx, client), Storage: data.Name.ValueString()}
+	if !data.Content.IsNull() {
+		if putReq.Content == nil {
+			putReq.Content = &[]string{}
+		}
+		resp.Diagnostics.Append(data.Content.ElementsAs(ctx, putReq.Content, false)...)
+	}
+	if !data.Nodes.IsNull() {
+		if putReq.Nodes == nil {
+			putReq.Nodes = &[]string{}
+		}
+		resp.Diagnostics.Append(data.Nodes.ElementsAs(ctx, putReq.Nodes, false)...)
+	}
+	if !data.Disable.IsNull() {
+		putReq.Disable = helpers.PtrTo(pvetypes.PVEBool(data.Disable.ValueBool()))
+	}
+	_, err := putReq.Put()
+	if err != nil {
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error updating "+r.typeName(), err, storageLVMThinAPIParams)
+		return
+	}
+
+	item, options, err := readStorageOptions(ctx, client, data.Name.ValueString())
+	if err != nil {
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error reading "+r.typeName(), err, nil)
+		return
+	}
+
+	resp.Diagnostics.Append(r.convertAPIGetResponseToTerraform(ctx, *item, options, data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	tflog.Trace(ctx, "updated "+r.typeName())
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, data)...)
+}
+
+func (r *StorageLVMThinResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data *StorageLVMThinResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := withTimeout(ctx, data.Timeouts.Delete, defaultDeleteTimeout, &resp.Diagnostics)
+	defer cancel()
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client := r.provider.client(ctx, r.typeName(), data.Auth, authToken, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := storage.ItemDeleteRequest{Client: withContext(ctx, client), Storage: data.Name.ValueString()}.Delete()
+	if err != nil {
+		if isNotFound(err, data.Name.ValueString()) {
+			return
+		}
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error deleting "+r.typeName(), err, nil)
+		return
+	}
+}
+
+func (r *StorageLVMThinResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resource.ImportStatePassthroughID(ctx, path.Root("name"), req, resp)
+}
+
+func (r *StorageLVMThinResource) convertAPIGetResponseToTerraform(ctx context.Context, apiData storage.ItemGetResponse, options storageOptions, tfData *StorageLVMThinResourceModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+	diags = append(diags, tfsdk.ValueFrom(ctx, apiData.Content, types.SetType{ElemType: types.StringType}, &tfData.Content)...)
+	diags = append(diags, tfsdk.ValueFrom(ctx, apiData.Nodes, types.SetType{ElemType: types.StringType}, &tfData.Nodes)...)
+
+	tfData.ID = types.StringValue(apiData.Storage)
+	tfData.Name = types.StringValue(apiData.Storage)
+	tfData.Type = types.StringValue(apiData.Type)
+	tfData.VGName = types.StringValue(options.string("vgname"))
+	tfData.ThinPool = types.StringValue(options.string("thinpool"))
+
+	tfData.Disable = types.BoolValue(apiData.Disable)
+
+	return diags
+}
@@ -0,0 +1,97 @@
+package provider
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+func TestAccStorageLVMThinResource(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			// Create and Read testing
+			{
+				Config: testAccStorageLVMThinResourceConfig([]string{"images"}, []string{"foobar"}),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("proxmoxve_storage_lvmthin.test", "id", "testacc_storage_lvmthin"),
+					resource.TestCheckResourceAttr("proxmoxve_storage_lvmthin.test", "vgname", "vg_test"),
+					resource.TestCheckResourceAttr("proxmoxve_storage_lvmthin.test", "thinpool", "data"),
+					resource.TestCheckResourceAttr("proxmoxve_storage_lvmthin.test", "disable", "false"),
+					resource.TestCheckResourceAttr("proxmoxve_storage_lvmthin.test", "type", "lvmthin"),
+					resource.TestCheckResourceAttr("proxmoxve_storage_lvmthin.test", "content.#", "1"),
+					resource.TestCheckTypeSetElemAttr("proxmoxve_storage_lvmthin.test", "content.*", "images"),
+					resource.TestCheckTypeSetElemAttr("proxmoxve_storage_lvmthin.test", "nodes.*", "foobar"),
+				),
+			},
+			// ImportState testing
+			{
+				ResourceName:      "proxmoxve_storage_lvmthin.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+			// Update and Read testing
+			{
+				Config: testAccStorageLVMThinResourceConfig([]string{"images", "rootdir"}, []string{"foo", "baz", "quux"}),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckTypeSetElemAttr("proxmoxve_storage_lvmthin.test", "content.*", "rootdir"),
+					resource.TestCheckTypeSetElemAttr("proxmoxve_storage_lvmthin.test", "nodes.*", "foo"),
+					resource.TestCheckTypeSetElemAttr("proxmoxve_storage_lvmthin.test", "nodes.*", "baz"),
+					resource.TestCheckTypeSetElemAttr("proxmoxve_storage_lvmthin.test", "nodes.*", "quux"),
+				),
+			},
+			// Delete testing automatically occurs in TestCase
+		},
+	})
+}
+
+// The import is not persisted, so that local-lvm is not deleted afterwards.
+func TestAccStorageLVMThinResourceImportLocalLVM(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+					resource "proxmoxve_storage_lvmthin" "local" {
+						name     = "local-lvm"
+						vgname   = "pve"
+						thinpool = "data"
+					}
+					`,
+				ResourceName:  "proxmoxve_storage_lvmthin.local",
+				ImportState:   true,
+				ImportStateId: "local-lvm",
+				ImportStateCheck: func(states []*terraform.InstanceState) error {
+					if len(states) != 1 {
+						return fmt.Errorf("expected 1 state, got %d", len(states))
+					}
+					for attr, expected := range map[string]string{
+						"id": "local-lvm", "vgname": "pve", "thinpool": "data", "type": "lvmthin", "content.#": "2", "disable": "false",
+					} {
+						if actual := states[0].Attributes[attr]; actual != expected {
+							return fmt.Errorf("expected %s to be %q, got %q", attr, expected, actual)
+						}
+					}
+					return nil
+				},
+			},
+		},
+	})
+}
+
+func testAccStorageLVMThinResourceConfig(content, nodes []string) string {
+	return fmt.Sprintf(`
+		resource "proxmoxve_storage_lvmthin" "test" {
+			name     = "testacc_storage_lvmthin"
+			vgname   = "vg_test"
+			thinpool = "data"
+			content  = ["%s"]
+			nodes    = ["%s"]
+		}
+		`, strings.Join(content, `","`), strings.Join(nodes, `","`))
+}
@@ -0,0 +1,507 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/c10l/proxmoxve-client-go/api/storage"
+	"github.com/c10l/proxmoxve-client-go/helpers"
+	pvetypes "github.com/c10l/proxmoxve-client-go/helpers/types"
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces
+var _ resource.Resource = &StoragePBSResource{}
+var _ resource.ResourceWithImportState = &StoragePBSResource{}
+var _ resource.ResourceWithModifyPlan = &StoragePBSResource{}
+
+func NewStoragePBSResource() resource.Resource {
+	return &StoragePBSResource{}
+}
+
+// StoragePBSResource defines the resource implementation.
+type StoragePBSResource struct {
+	provider *providerData
+}
+
+// StoragePBSResource describes the resource data model.
+type StoragePBSResourceModel struct {
+	ID types.String `tfsdk:"id"`
+
+	// Required attributes
+	Name      types.String `tfsdk:"name"`
+	Server    types.String `tfsdk:"server"`
+	Datastore types.String `tfsdk:"datastore"`
+
+	// Optional attributes
+	Port          types.Int64  `tfsdk:"port"`
+	Username      types.String `tfsdk:"username"`
+	Password      types.String `tfsdk:"password"`
+	Namespace     types.String `tfsdk:"namespace"`
+	Fingerprint   types.String `tfsdk:"fingerprint"`
+	EncryptionKey types.String `tfsdk:"encryption_key"`
+	MasterPubkey  types.String `tfsdk:"master_pubkey"`
+	Content       types.Set    `tfsdk:"content"`
+	Nodes         types.Set    `tfsdk:"nodes"`
+	Disable       types.Bool   `tfsdk:"disable"`
+
+	// Computed attributes
+	GeneratedEncryptionKey types.String `tfsdk:"generated_encryption_key"`
+	Type                   types.String `tfsdk:"type"`
+	PruneBackups           types.String `tfsdk:"prune_backups"`
+
+	Auth     types.String   `tfsdk:"auth"`
+	Timeouts timeouts.Value `tfsdk:"timeouts"`
+}
+
+// storagePBSAPIParams maps the API parameters whose name differs from their attribute.
+var storagePBSAPIParams = map[string]string{
+	"storage":        "name",
+	"encryption-key": "encryption_key",
+	"master-pubkey":  "master_pubkey",
+}
+
+// storagePBSVersions: Proxmox Backup Server storages were introduced by
+// Proxmox VE 6.2, and namespaces by 7.2.
+var storagePBSVersions = versionRequirements{
+	Resource:   pveRelease{Major: 6, Minor: 2},
+	Attributes: map[string]pveRelease{"namespace": {Major: 7, Minor: 2}},
+}
+
+// storagePBSDefaultPort is the port of the API of Proxmox Backup Server,
+// unless set otherwise.
+const storagePBSDefaultPort = 8007
+
+// storagePBSAutogenKey is the encryption key which has Proxmox VE generate one.
+const storagePBSAutogenKey = "autogen"
+
+func (r *StoragePBSResource) typeName() string { return "storage_pbs" }
+
+func (r *StoragePBSResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_" + r.typeName()
+}
+
+func (r *StoragePBSResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Storage on a Proxmox Backup Server datastore, for backups." +
+			"<p />**NOTE:** Proxmox VE never returns `password`, `encryption_key` or `master_pubkey`, so their changes made outside of Terraform are not detected. " +
+			"Each is only sent when changed, as told by a hash of the last one set kept in the private state of the resource. " +
+			"Like any attribute, they and `generated_encryption_key` are stored in the Terraform state, where they are marked as sensitive.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+			},
+			"name": schema.StringAttribute{
+				Required:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"server": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "Name or IP address of the Proxmox Backup Server.",
+			},
+			"datastore": schema.StringAttribute{
+				Required:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.RequiresReplace()},
+				MarkdownDescription: "Name of the datastore on the server.",
+			},
+			"port": schema.Int64Attribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: fmt.Sprintf("Port of the server. Defaults to `%d`.", storagePBSDefaultPort),
+			},
+			"username": schema.StringAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "User or API token to authenticate as, e.g. `backup@pbs` or `backup@pbs!pve`.",
+			},
+			"password": schema.StringAttribute{
+				Optional:            true,
+				Sensitive:           true,
+				MarkdownDescription: "Password or API token secret of `username`.",
+			},
+			"namespace": schema.StringAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "Namespace of the datastore to use, rather than its root. Requires PVE >= 7.2.",
+			},
+			"fingerprint": schema.StringAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "SHA-256 fingerprint of the certificate of the server, needed unless it is trusted by the nodes.",
+			},
+			"encryption_key": schema.StringAttribute{
+				Optional:  true,
+				Sensitive: true,
+				MarkdownDescription: fmt.Sprintf("Key encrypting the backups client-side, in the JSON format of `proxmox-backup-client key create`, "+
+					"or `%s` to have Proxmox VE generate one, then returned by `generated_encryption_key`. "+
+					"Setting `%s` on a storage which already has an encryption key not set by Terraform, e.g. after an import, keeps that key rather than generating another one.",
+					storagePBSAutogenKey, storagePBSAutogenKey),
+			},
+			"master_pubkey": schema.StringAttribute{
+				Optional:  true,
+				Sensitive: true,
+				MarkdownDescription: "Base64-encoded PEM RSA public key encrypting a copy of the encryption key added to every backup, " +
+					"so that it can be recovered with the matching private key.",
+			},
+			"content": schema.SetAttribute{
+				ElementType:         types.StringType,
+				Optional:            true,
+				Computed:            true,
+				Validators:          []validator.Set{storageContentValidator{storage.TypePBS, []string{storage.ContentBackup}}},
+				MarkdownDescription: "Content types stored: only `backup`.",
+			},
+			"nodes": schema.SetAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Computed:    true,
+			},
+			"disable": schema.BoolAttribute{
+				Optional: true,
+				Computed: true,
+			},
+			"generated_encryption_key": schema.StringAttribute{
+				Computed:  true,
+				Sensitive: true,
+				MarkdownDescription: fmt.Sprintf("Encryption key generated by Proxmox VE when `encryption_key` is set to `%s`. "+
+					"Keep a copy of it elsewhere: backups cannot be restored without it. "+
+					"It is only known to the Terraform state of the resource which requested it, and is null after an import.", storagePBSAutogenKey),
+			},
+			"type": schema.StringAttribute{
+				Computed: true,
+			},
+			"prune_backups": schema.StringAttribute{
+				Computed: true,
+			},
+			"auth": authAttribute(authToken),
+		},
+		Blocks: map[string]schema.Block{
+			"timeouts": timeoutsBlock(ctx),
+		},
+	}
+}
+
+func (r *StoragePBSResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	r.provider = configureProviderData(req.ProviderData, &resp.Diagnostics)
+}
+
+func (r *StoragePBSResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	r.provider.checkPrivileges(ctx, r.typeName(), authToken, storagePrivileges, req, resp)
+	r.provider.checkVersion(ctx, r.typeName(), storagePBSVersions, req, resp)
+}
+
+func (r *StoragePBSResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data *StoragePBSResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := withTimeout(ctx, data.Timeouts.Create, defaultCreateTimeout, &resp.Diagnostics)
+	defer cancel()
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client := r.provider.client(ctx, r.typeName(), data.Auth, authToken, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	postReq := storagePostRequest{
+		PostRequest: storage.PostRequest{
+			Client:      withContext(ctx, client),
+			Storage:     data.Name.ValueString(),
+			StorageType: storage.TypePBS,
+		},
+		Params: r.params(data),
+	}
+	postReq.Params.Set("datastore", data.Datastore.ValueString())
+	for param, value := range r.secrets(data) {
+		if !value.IsNull() {
+			postReq.Params.Set(param, value.ValueString())
+		}
+	}
+	if !data.Content.IsNull() {
+		if postReq.Content == nil {
+			postReq.Content = &[]string{}
+		}
+		resp.Diagnostics.Append(data.Content.ElementsAs(ctx, postReq.Content, false)...)
+	}
+	if !data.Nodes.IsNull() {
+		if postReq.Nodes == nil {
+			postReq.Nodes = &[]string{}
+		}
+		resp.Diagnostics.Append(data.Nodes.ElementsAs(ctx, postReq.Nodes, false)...)
+	}
+	if !data.Disable.IsNull() {
+		postReq.Disable = helpers.PtrTo(pvetypes.PVEBool(data.Disable.ValueBool()))
+	}
+	postResp, err := postReq.Post()
+	if err != nil {
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error creating "+r.typeName(), err, storagePBSAPIParams)
+		return
+	}
+	resp.Diagnostics.Append(keepStorageSecretHashes(ctx, resp.Private, r.secrets(data))...)
+	data.GeneratedEncryptionKey = r.generatedEncryptionKey(postResp, types.StringNull())
+
+	item, options, err := readStorageOptions(ctx, client, data.Name.ValueString())
+	if err != nil {
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error reading "+r.typeName(), err, nil)
+		return
+	}
+
+	resp.Diagnostics.Append(r.convertAPIGetResponseToTerraform(ctx, *item, options, data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	tflog.Trace(ctx, "created "+r.typeName())
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, data)...)
+}
+
+func (r *StoragePBSResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data *StoragePBSResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := withTimeout(ctx, data.Timeouts.Read, defaultReadTimeout, &resp.Diagnostics)
+	defer cancel()
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client := r.provider.client(ctx, r.typeName(), data.Auth, authToken, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	item, options, err := readStorageOptions(ctx, client, data.Name.ValueString())
+	if err != nil {
+		// If resource has been deleted outside of Terraform, we remove it from the plan state so it can be re-created.
+		if isNotFound(err, data.Name.ValueString()) {
+			resp.State.RemoveResource(ctx)
+			return
+		}
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error reading "+r.typeName(), err, nil)
+		return
+	}
+
+	if item.Type != storage.TypePBS {
+		resp.Diagnostics.AddError("Wrong storage type", fmt.Sprintf("Storage %s is of type %s but is declared as "+r.typeName(), data.Name.ValueString(), item.Type))
+		return
+	}
+
+	resp.Diagnostics.Append(r.convertAPIGetResponseToTerraform(ctx, *item, options, data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, data)...)
+}
+
+func (r *StoragePBSResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data *StoragePBSResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := withTimeout(ctx, data.Timeouts.Update, defaultUpdateTimeout, &resp.Diagnostics)
+	defer cancel()
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client := r.provider.client(ctx, r.typeName(), data.Auth, authToken, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	secrets := r.secrets(data)
+	if data.EncryptionKey.ValueString() == storagePBSAutogenKey && !storageSecretChanged(ctx, req.Private, "encryption-key", types.StringNull(), &resp.Diagnostics) {
+		// The storage may have an encryption key not set by Terraform, e.g.
+		// after an import: it is kept rather than replaced by another one
+		// generated.
+		_, options, err := readStorageOptions(ctx, client, data.Name.ValueString())
+		if err != nil {
+			addAPIErrorDiagnostics(&resp.Diagnostics, "Error reading "+r.typeName(), err, nil)
+			return
+		}
+		if options.string("encryption-key") != "" {
+			delete(secrets, "encryption-key")
+		}
+	}
+
+	// The generated encryption key is kept until the encryption key changes.
+	var generatedKey types.String
+	resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("generated_encryption_key"), &generatedKey)...)
+	if _, ok := secrets["encryption-key"]; ok && storageSecretChanged(ctx, req.Private, "encryption-key", data.EncryptionKey, &resp.Diagnostics) {
+		generatedKey = types.StringNull()
+	}
+
+	putReq := storagePutRequest{
+		ItemPutRequest: storage.ItemPutRequest{Client: withContext(ctx, client), Storage: data.Name.ValueString()},
+		Params:         r.params(data),
+	}
+	setChangedStorageSecrets(ctx, req.Private, putReq.Params, secrets, &resp.Diagnostics)
+	if !data.Content.IsNull() {
+		if putReq.Content == nil {
+			putReq.Content = &[]string{}
+		}
+		resp.Diagnostics.Append(data.Content.ElementsAs(ctx, putReq.Content, false)...)
+	}
+	if !data.Nodes.IsNull() {
+		if putReq.Nodes == nil {
+			putReq.Nodes = &[]string{}
+		}
+		resp.Diagnostics.Append(data.Nodes.ElementsAs(ctx, putReq.Nodes, false)...)
+	}
+	if !data.Disable.IsNull() {
+		putReq.Disable = helpers.PtrTo(pvetypes.PVEBool(data.Disable.ValueBool()))
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	putResp, err := putReq.Put()
+	if err != nil {
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error updating "+r.typeName(), err, storagePBSAPIParams)
+		return
+	}
+	resp.Diagnostics.Append(keepStorageSecretHashes(ctx, resp.Private, r.secrets(data))...)
+	data.GeneratedEncryptionKey = r.generatedEncryptionKey(putResp, generatedKey)
+
+	item, options, err := readStorageOptions(ctx, client, data.Name.ValueString())
+	if err != nil {
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error reading "+r.typeName(), err, nil)
+		return
+	}
+
+	resp.Diagnostics.Append(r.convertAPIGetResponseToTerraform(ctx, *item, options, data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	tflog.Trace(ctx, "updated "+r.typeName())
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, data)...)
+}
+
+func (r *StoragePBSResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data *StoragePBSResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := withTimeout(ctx, data.Timeouts.Delete, defaultDeleteTimeout, &resp.Diagnostics)
+	defer cancel()
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client := r.provider.client(ctx, r.typeName(), data.Auth, authToken, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := storage.ItemDeleteRequest{Client: withContext(ctx, client), Storage: data.Name.ValueString()}.Delete()
+	if err != nil {
+		if isNotFound(err, data.Name.ValueString()) {
+			return
+		}
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error deleting "+r.typeName(), err, nil)
+		return
+	}
+}
+
+func (r *StoragePBSResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resource.ImportStatePassthroughID(ctx, path.Root("name"), req, resp)
+	// The secrets in use are not known: those configured are sent by the next
+	// update.
+	resp.Diagnostics.Append(keepStorageSecretHashes(ctx, resp.Private, map[string]types.String{
+		"password":       types.StringNull(),
+		"encryption-key": types.StringNull(),
+		"master-pubkey":  types.StringNull(),
+	})...)
+}
+
+// params returns the PBS options which can be both created and updated, and
+// which storage.PostRequest and storage.ItemPutRequest lack. The secrets are
+// left out: they are only sent when changed.
+func (r *StoragePBSResource) params(data *StoragePBSResourceModel) url.Values {
+	params := url.Values{}
+	for name, value := range map[string]types.String{
+		"server":      data.Server,
+		"username":    data.Username,
+		"namespace":   data.Namespace,
+		"fingerprint": data.Fingerprint,
+	} {
+		if !value.IsNull() {
+			params.Set(name, value.ValueString())
+		}
+	}
+	if !data.Port.IsNull() {
+		params.Set("port", strconv.FormatInt(data.Port.ValueInt64(), 10))
+	}
+	return params
+}
+
+// secrets returns the options never returned by the API, by parameter.
+func (r *StoragePBSResource) secrets(data *StoragePBSResourceModel) map[string]types.String {
+	return map[string]types.String{
+		"password":       data.Password,
+		"encryption-key": data.EncryptionKey,
+		"master-pubkey":  data.MasterPubkey,
+	}
+}
+
+// generatedEncryptionKey returns the encryption key generated by the creation
+// or the update of a storage, or previous if none was.
+func (r *StoragePBSResource) generatedEncryptionKey(apiResp *storageResponse, previous types.String) types.String {
+	if apiResp != nil {
+		if key := apiResp.Config.string("encryption-key"); key != "" {
+			return types.StringValue(key)
+		}
+	}
+	return previous
+}
+
+// convertAPIGetResponseToTerraform sets the attributes of tfData read from the
+// API, which never returns the secrets nor the generated encryption key: they
+// are left as they are.
+func (r *StoragePBSResource) convertAPIGetResponseToTerraform(ctx context.Context, apiData storage.ItemGetResponse, options storageOptions, tfData *StoragePBSResourceModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+	diags = append(diags, tfsdk.ValueFrom(ctx, apiData.Content, types.SetType{ElemType: types.StringType}, &tfData.Content)...)
+	diags = append(diags, tfsdk.ValueFrom(ctx, apiData.Nodes, types.SetType{ElemType: types.StringType}, &tfData.Nodes)...)
+
+	tfData.ID = types.StringValue(apiData.Storage)
+	tfData.Name = types.StringValue(apiData.Storage)
+	tfData.Type = types.StringValue(apiData.Type)
+	tfData.PruneBackups = types.StringValue(apiData.PruneBackups)
+	tfData.Server = types.StringValue(options.string("server"))
+	tfData.Datastore = types.StringValue(options.string("datastore"))
+	tfData.Port = types.Int64Value(options.int64("port", storagePBSDefaultPort))
+	tfData.Username = types.StringValue(options.string("username"))
+	tfData.Namespace = types.StringValue(options.string("namespace"))
+	tfData.Fingerprint = types.StringValue(options.string("fingerprint"))
+
+	tfData.Disable = types.BoolValue(apiData.Disable)
+
+	return diags
+}
@@ -0,0 +1,129 @@
+package provider
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+)
+
+func TestAccStoragePBSResource(t *testing.T) {
+	var generatedKey string
+	keepGeneratedKey := func(value string) error {
+		generatedKey = value
+		return nil
+	}
+	sameGeneratedKey := func(value string) error {
+		if value != generatedKey {
+			return fmt.Errorf("the generated encryption key changed")
+		}
+		return nil
+	}
+	// The generated key is only known once the checks run.
+	checkGeneratedKeySet := func(s *terraform.State) error {
+		return testAccCheckStorageSecret("testacc_storage_pbs", "encryption-key", generatedKey)(s)
+	}
+	const key = `{"data":"00112233","fingerprint":"12:34:56","kdf":null}`
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			// Create and Read testing
+			{
+				Config: testAccStoragePBSResourceConfig("secret", `"autogen"`, "8007"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("proxmoxve_storage_pbs.test", "id", "testacc_storage_pbs"),
+					resource.TestCheckResourceAttr("proxmoxve_storage_pbs.test", "server", "pbs.example.com"),
+					resource.TestCheckResourceAttr("proxmoxve_storage_pbs.test", "datastore", "backups"),
+					resource.TestCheckResourceAttr("proxmoxve_storage_pbs.test", "port", "8007"),
+					resource.TestCheckResourceAttr("proxmoxve_storage_pbs.test", "username", "backup@pbs!pve"),
+					resource.TestCheckResourceAttr("proxmoxve_storage_pbs.test", "password", "secret"),
+					resource.TestCheckResourceAttr("proxmoxve_storage_pbs.test", "fingerprint", "ab:cd:ef"),
+					resource.TestCheckResourceAttr("proxmoxve_storage_pbs.test", "encryption_key", "autogen"),
+					resource.TestCheckResourceAttrWith("proxmoxve_storage_pbs.test", "generated_encryption_key", testAccRegexpMatch(`^\{.*"fingerprint":`)),
+					resource.TestCheckResourceAttrWith("proxmoxve_storage_pbs.test", "generated_encryption_key", keepGeneratedKey),
+					resource.TestCheckResourceAttr("proxmoxve_storage_pbs.test", "type", "pbs"),
+					resource.TestCheckTypeSetElemAttr("proxmoxve_storage_pbs.test", "content.*", "backup"),
+					testAccCheckStorageSecret("testacc_storage_pbs", "password", "secret"),
+					checkGeneratedKeySet,
+				),
+			},
+			// ImportState testing
+			{
+				ResourceName:      "proxmoxve_storage_pbs.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+				// The secrets are never returned by the API.
+				ImportStateVerifyIgnore: []string{"password", "encryption_key", "generated_encryption_key"},
+			},
+			// Update and Read testing: the generated key is kept.
+			{
+				Config: testAccStoragePBSResourceConfig("secret", `"autogen"`, "8008"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("proxmoxve_storage_pbs.test", "port", "8008"),
+					resource.TestCheckResourceAttrWith("proxmoxve_storage_pbs.test", "generated_encryption_key", sameGeneratedKey),
+					checkGeneratedKeySet,
+				),
+			},
+			// The secrets changed are sent, and the generated key is forgotten.
+			{
+				Config: testAccStoragePBSResourceConfig("changed", fmt.Sprintf("%q", key), "8008"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("proxmoxve_storage_pbs.test", "password", "changed"),
+					resource.TestCheckResourceAttr("proxmoxve_storage_pbs.test", "encryption_key", key),
+					resource.TestCheckNoResourceAttr("proxmoxve_storage_pbs.test", "generated_encryption_key"),
+					testAccCheckStorageSecret("testacc_storage_pbs", "password", "changed"),
+					testAccCheckStorageSecret("testacc_storage_pbs", "encryption-key", key),
+				),
+			},
+			// Setting autogen instead generates another key.
+			{
+				Config: testAccStoragePBSResourceConfig("changed", `"autogen"`, "8008"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("proxmoxve_storage_pbs.test", "encryption_key", "autogen"),
+					resource.TestCheckResourceAttrWith("proxmoxve_storage_pbs.test", "generated_encryption_key", keepGeneratedKey),
+					checkGeneratedKeySet,
+				),
+			},
+			// Without encryption key, there is no generated one.
+			{
+				Config: testAccStoragePBSResourceConfig("changed", "null", "8008"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckNoResourceAttr("proxmoxve_storage_pbs.test", "encryption_key"),
+					resource.TestCheckNoResourceAttr("proxmoxve_storage_pbs.test", "generated_encryption_key"),
+					testAccCheckStorageSecret("testacc_storage_pbs", "encryption-key", ""),
+				),
+			},
+			// A key set outside of Terraform is kept by autogen.
+			{
+				SkipFunc: func() (bool, error) { return testAccMockServer == nil, nil },
+				PreConfig: func() {
+					testAccMockServer.SetStorageOption("testacc_storage_pbs", "encryption-key", key)
+				},
+				Config: testAccStoragePBSResourceConfig("changed", `"autogen"`, "8008"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckNoResourceAttr("proxmoxve_storage_pbs.test", "generated_encryption_key"),
+					testAccCheckStorageSecret("testacc_storage_pbs", "encryption-key", key),
+				),
+			},
+			// Delete testing automatically occurs in TestCase
+		},
+	})
+}
+
+func testAccStoragePBSResourceConfig(password, encryptionKey, port string) string {
+	return fmt.Sprintf(`
+		resource "proxmoxve_storage_pbs" "test" {
+			name           = "testacc_storage_pbs"
+			server         = "pbs.example.com"
+			datastore      = "backups"
+			port           = %s
+			username       = "backup@pbs!pve"
+			password       = %q
+			fingerprint    = "ab:cd:ef"
+			encryption_key = %s
+		}
+		`, port, password, encryptionKey)
+}
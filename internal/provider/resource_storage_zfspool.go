@@ -0,0 +1,366 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/c10l/proxmoxve-client-go/api/storage"
+	"github.com/c10l/proxmoxve-client-go/helpers"
+	pvetypes "github.com/c10l/proxmoxve-client-go/helpers/types"
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces
+var _ resource.Resource = &StorageZFSPoolResource{}
+var _ resource.ResourceWithImportState = &StorageZFSPoolResource{}
+var _ resource.ResourceWithModifyPlan = &StorageZFSPoolResource{}
+
+func NewStorageZFSPoolResource() resource.Resource {
+	return &StorageZFSPoolResource{}
+}
+
+// StorageZFSPoolResource defines the resource implementation.
+type StorageZFSPoolResource struct {
+	provider *providerData
+}
+
+// StorageZFSPoolResource describes the resource data model.
+type StorageZFSPoolResourceModel struct {
+	ID types.String `tfsdk:"id"`
+
+	// Required attributes
+	Name types.String `tfsdk:"name"`
+	Pool types.String `tfsdk:"pool"`
+
+	// Optional attributes
+	Content    types.Set    `tfsdk:"content"`
+	Nodes      types.Set    `tfsdk:"nodes"`
+	Disable    types.Bool   `tfsdk:"disable"`
+	BlockSize  types.String `tfsdk:"blocksize"`
+	Sparse     types.Bool   `tfsdk:"sparse"`
+	MountPoint types.String `tfsdk:"mountpoint"`
+
+	// Computed attributes
+	Type types.String `tfsdk:"type"`
+
+	Auth     types.String   `tfsdk:"auth"`
+	Timeouts timeouts.Value `tfsdk:"timeouts"`
+}
+
+// storageZFSPoolAPIParams maps the API parameters whose name differs from their attribute.
+var storageZFSPoolAPIParams = map[string]string{"storage": "name"}
+
+func (r *StorageZFSPoolResource) typeName() string { return "storage_zfspool" }
+
+func (r *StorageZFSPoolResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_" + r.typeName()
+}
+
+func (r *StorageZFSPoolResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Storage backed by a ZFS pool or dataset, with a volume per disk image and a dataset per container.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed: true,
+			},
+			"name": schema.StringAttribute{
+				Required:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"pool": schema.StringAttribute{
+				Required:            true,
+				PlanModifiers:       []planmodifier.String{stringplanmodifier.RequiresReplace()},
+				MarkdownDescription: "Name of the ZFS pool or dataset volumes are created in, e.g. `rpool/data`.",
+			},
+			"content": schema.SetAttribute{
+				ElementType:         types.StringType,
+				Optional:            true,
+				Computed:            true,
+				Validators:          []validator.Set{storageContentValidator{storage.TypeZFSPool, []string{storage.ContentImages, storage.ContentRootDir}}},
+				MarkdownDescription: "Content types stored: `images` and/or `rootdir`.",
+			},
+			"nodes": schema.SetAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Computed:    true,
+			},
+			"disable": schema.BoolAttribute{
+				Optional: true,
+				Computed: true,
+			},
+			"blocksize": schema.StringAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "Block size of the volumes created, e.g. `16k`.",
+			},
+			"sparse": schema.BoolAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "Whether to create thinly provisioned volumes, without reserving their size.",
+			},
+			"mountpoint": schema.StringAttribute{
+				Optional:            true,
+				Computed:            true,
+				MarkdownDescription: "Mount point of the pool or dataset, if not the default `/<pool>`.",
+			},
+			"type": schema.StringAttribute{
+				Computed: true,
+			},
+			"auth": authAttribute(authToken),
+		},
+		Blocks: map[string]schema.Block{
+			"timeouts": timeoutsBlock(ctx),
+		},
+	}
+}
+
+func (r *StorageZFSPoolResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	r.provider = configureProviderData(req.ProviderData, &resp.Diagnostics)
+}
+
+func (r *StorageZFSPoolResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	r.provider.checkPrivileges(ctx, r.typeName(), authToken, storagePrivileges, req, resp)
+}
+
+func (r *StorageZFSPoolResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data *StorageZFSPoolResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := withTimeout(ctx, data.Timeouts.Create, defaultCreateTimeout, &resp.Diagnostics)
+	defer cancel()
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client := r.provider.client(ctx, r.typeName(), data.Auth, authToken, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	postReq := storagePostRequest{
+		PostRequest: storage.PostRequest{
+			Client:      withContext(ctx, client),
+			Storage:     data.Name.ValueString(),
+			StorageType: storage.TypeZFSPool,
+		},
+		Params: r.params(data),
+	}
+	postReq.Params.Set("pool", data.Pool.ValueString())
+	if !data.Content.IsNull() {
+		if postReq.Content == nil {
+			postReq.Content = &[]string{}
+		}
+		resp.Diagnostics.Append(data.Content.ElementsAs(ctx, postReq.Content, false)...)
+	}
+	if !data.Nodes.IsNull() {
+		if postReq.Nodes == nil {
+			postReq.Nodes = &[]string{}
+		}
+		resp.Diagnostics.Append(data.Nodes.ElementsAs(ctx, postReq.Nodes, false)...)
+	}
+	if !data.Disable.IsNull() {
+		postReq.Disable = helpers.PtrTo(pvetypes.PVEBool(data.Disable.ValueBool()))
+	}
+	_, err := postReq.Post()
+	if err != nil {
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error creating "+r.typeName(), err, storageZFSPoolAPIParams)
+		return
+	}
+
+	item, options, err := readStorageOptions(ctx, client, data.Name.ValueString())
+	if err != nil {
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error reading "+r.typeName(), err, nil)
+		return
+	}
+
+	resp.Diagnostics.Append(r.convertAPIGetResponseToTerraform(ctx, *item, options, data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	tflog.Trace(ctx, "created "+r.typeName())
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, data)...)
+}
+
+func (r *StorageZFSPoolResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data *StorageZFSPoolResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := withTimeout(ctx, data.Timeouts.Read, defaultReadTimeout, &resp.Diagnostics)
+	defer cancel()
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client := r.provider.client(ctx, r.typeName(), data.Auth, authToken, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	item, options, err := readStorageOptions(ctx, client, data.Name.ValueString())
+	if err != nil {
+		// If resource has been deleted outside of Terraform, we remove it from the plan state so it can be re-created.
+		if isNotFound(err, data.Name.ValueString()) {
+			resp.State.RemoveResource(ctx)
+			return
+		}
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error reading "+r.typeName(), err, nil)
+		return
+	}
+
+	if item.Type != storage.TypeZFSPool {
+		resp.Diagnostics.AddError("Wrong storage type", fmt.Sprintf("Storage %s is of type %s but is declared as "+r.typeName(), data.Name.ValueString(), item.Type))
+		return
+	}
+
+	resp.Diagnostics.Append(r.convertAPIGetResponseToTerraform(ctx, *item, options, data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, data)...)
+}
+
+func (r *StorageZFSPoolResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data *StorageZFSPoolResourceModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := withTimeout(ctx, data.Timeouts.Update, defaultUpdateTimeout, &resp.Diagnostics)
+	defer cancel()
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client := r.provider.client(ctx, r.typeName(), data.Auth, authToken, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	putReq := storagePutRequest{
+		ItemPutRequest: storage.ItemPutRequest{Client: withContext(ctx, client), Storage: data.Name.ValueString()},
+		Params:         r.params(data),
+	}
+	if !data.Content.IsNull() {
+		if putReq.Content == nil {
+			putReq.Content = &[]string{}
+		}
+		resp.Diagnostics.Append(data.Content.ElementsAs(ctx, putReq.Content, false)...)
+	}
+	if !data.Nodes.IsNull() {
+		if putReq.Nodes == nil {
+			putReq.Nodes = &[]string{}
+		}
+		resp.Diagnostics.Append(data.Nodes.ElementsAs(ctx, putReq.Nodes, false)...)
+	}
+	if !data.Disable.IsNull() {
+		putReq.Disable = helpers.PtrTo(pvetypes.PVEBool(data.Disable.ValueBool()))
+	}
+	_, err := putReq.Put()
+	if err != nil {
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error updating "+r.typeName(), err, storageZFSPoolAPIParams)
+		return
+	}
+
+	item, options, err := readStorageOptions(ctx, client, data.Name.ValueString())
+	if err != nil {
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error reading "+r.typeName(), err, nil)
+		return
+	}
+
+	resp.Diagnostics.Append(r.convertAPIGetResponseToTerraform(ctx, *item, options, data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	tflog.Trace(ctx, "updated "+r.typeName())
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, data)...)
+}
+
+func (r *StorageZFSPoolResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data *StorageZFSPoolResourceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := withTimeout(ctx, data.Timeouts.Delete, defaultDeleteTimeout, &resp.Diagnostics)
+	defer cancel()
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client := r.provider.client(ctx, r.typeName(), data.Auth, authToken, &resp.Diagnostics)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := storage.ItemDeleteRequest{Client: withContext(ctx, client), Storage: data.Name.ValueString()}.Delete()
+	if err != nil {
+		if isNotFound(err, data.Name.ValueString()) {
+			return
+		}
+		addAPIErrorDiagnostics(&resp.Diagnostics, "Error deleting "+r.typeName(), err, nil)
+		return
+	}
+}
+
+func (r *StorageZFSPoolResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resource.ImportStatePassthroughID(ctx, path.Root("name"), req, resp)
+}
+
+// params returns the ZFS options which can be both created and updated, and
+// which storage.PostRequest and storage.ItemPutRequest lack.
+func (r *StorageZFSPoolResource) params(data *StorageZFSPoolResourceModel) url.Values {
+	params := url.Values{}
+	if !data.BlockSize.IsNull() {
+		params.Set("blocksize", data.BlockSize.ValueString())
+	}
+	if !data.Sparse.IsNull() {
+		params.Set("sparse", pvetypes.PVEBool(data.Sparse.ValueBool()).ToAPIRequestParam())
+	}
+	if !data.MountPoint.IsNull() {
+		params.Set("mountpoint", data.MountPoint.ValueString())
+	}
+	return params
+}
+
+func (r *StorageZFSPoolResource) convertAPIGetResponseToTerraform(ctx context.Context, apiData storage.ItemGetResponse, options storageOptions, tfData *StorageZFSPoolResourceModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+	diags = append(diags, tfsdk.ValueFrom(ctx, apiData.Content, types.SetType{ElemType: types.StringType}, &tfData.Content)...)
+	diags = append(diags, tfsdk.ValueFrom(ctx, apiData.Nodes, types.SetType{ElemType: types.StringType}, &tfData.Nodes)...)
+
+	tfData.ID = types.StringValue(apiData.Storage)
+	tfData.Name = types.StringValue(apiData.Storage)
+	tfData.Type = types.StringValue(apiData.Type)
+	tfData.Pool = types.StringValue(options.string("pool"))
+	tfData.BlockSize = types.StringValue(options.string("blocksize"))
+	tfData.MountPoint = types.StringValue(options.string("mountpoint"))
+
+	tfData.Disable = types.BoolValue(apiData.Disable)
+	tfData.Sparse = types.BoolValue(options.bool("sparse"))
+
+	return diags
+}
@@ -0,0 +1,63 @@
+package provider
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestAccStorageZFSPoolResource(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			// Create and Read testing
+			{
+				Config: testAccStorageZFSPoolResourceConfig(false, []string{"foobar"}),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("proxmoxve_storage_zfspool.test", "id", "testacc_storage_zfspool"),
+					resource.TestCheckResourceAttr("proxmoxve_storage_zfspool.test", "pool", "tank/pve"),
+					resource.TestCheckResourceAttr("proxmoxve_storage_zfspool.test", "blocksize", "16k"),
+					resource.TestCheckResourceAttr("proxmoxve_storage_zfspool.test", "sparse", "false"),
+					resource.TestCheckResourceAttr("proxmoxve_storage_zfspool.test", "mountpoint", ""),
+					resource.TestCheckResourceAttr("proxmoxve_storage_zfspool.test", "disable", "false"),
+					resource.TestCheckResourceAttr("proxmoxve_storage_zfspool.test", "type", "zfspool"),
+					resource.TestCheckTypeSetElemAttr("proxmoxve_storage_zfspool.test", "content.*", "images"),
+					resource.TestCheckTypeSetElemAttr("proxmoxve_storage_zfspool.test", "content.*", "rootdir"),
+					resource.TestCheckTypeSetElemAttr("proxmoxve_storage_zfspool.test", "nodes.*", "foobar"),
+				),
+			},
+			// ImportState testing
+			{
+				ResourceName:      "proxmoxve_storage_zfspool.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+			// Update and Read testing
+			{
+				Config: testAccStorageZFSPoolResourceConfig(true, []string{"foo", "baz", "quux"}),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckTypeSetElemAttr("proxmoxve_storage_zfspool.test", "nodes.*", "foo"),
+					resource.TestCheckTypeSetElemAttr("proxmoxve_storage_zfspool.test", "nodes.*", "baz"),
+					resource.TestCheckTypeSetElemAttr("proxmoxve_storage_zfspool.test", "nodes.*", "quux"),
+					resource.TestCheckResourceAttr("proxmoxve_storage_zfspool.test", "sparse", "true"),
+				),
+			},
+			// Delete testing automatically occurs in TestCase
+		},
+	})
+}
+
+func testAccStorageZFSPoolResourceConfig(sparse bool, nodes []string) string {
+	return fmt.Sprintf(`
+		resource "proxmoxve_storage_zfspool" "test" {
+			name      = "testacc_storage_zfspool"
+			pool      = "tank/pve"
+			blocksize = "16k"
+			sparse    = %t
+			nodes     = ["%s"]
+		}
+		`, sparse, strings.Join(nodes, `","`))
+}
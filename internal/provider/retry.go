@@ -0,0 +1,188 @@
+package provider
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	proxmox "github.com/c10l/proxmoxve-client-go/api"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// retryPolicy controls how API calls failing with a transient error are retried.
+type retryPolicy struct {
+	MaxRetries int
+	BackoffMin time.Duration
+	BackoffMax time.Duration
+}
+
+var defaultRetryPolicy = retryPolicy{
+	MaxRetries: 4,
+	BackoffMin: 1 * time.Second,
+	BackoffMax: 30 * time.Second,
+}
+
+// backoff returns the delay before retry number attempt (starting at 0): it
+// doubles from BackoffMin on every attempt up to BackoffMax.
+func (p retryPolicy) backoff(attempt int) time.Duration {
+	wait := p.BackoffMin
+	for i := 0; i < attempt && wait < p.BackoffMax; i++ {
+		wait *= 2
+	}
+	if wait > p.BackoffMax {
+		wait = p.BackoffMax
+	}
+	return wait
+}
+
+// sleep waits for the backoff of attempt, returning early with the context
+// error if ctx is done first.
+func (p retryPolicy) sleep(ctx context.Context, attempt int) error {
+	timer := time.NewTimer(p.backoff(attempt))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// poll calls f until it reports done or fails, waiting between calls as
+// configured by the policy. Transient API errors are already retried by the
+// client, so f should only report not done for operations still in progress.
+// Polling is bounded by the deadline of ctx.
+func (p retryPolicy) poll(ctx context.Context, f func() (bool, error)) error {
+	for attempt := 0; ; attempt++ {
+		done, err := f()
+		if err != nil || done {
+			return err
+		}
+		if err := p.sleep(ctx, attempt); err != nil {
+			return err
+		}
+	}
+}
+
+// retryTransport retries requests failing with a transient error: a timeout
+// acquiring a cluster lock, or a gateway or availability error on a GET or
+// DELETE request, or a connection failure on a GET request. A proxy may
+// report a gateway error once the node has applied the request, so creations
+// and updates are not retried then: a creation would fail, the object
+// existing already.
+type retryTransport struct {
+	next   http.RoundTripper
+	policy retryPolicy
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	for attempt := 0; ; attempt++ {
+		resp, err := t.next.RoundTrip(req)
+		if attempt >= t.policy.MaxRetries || !isRetryable(req, resp, err) || !rewindBody(req) {
+			return resp, err
+		}
+
+		status := ""
+		if err != nil {
+			status = err.Error()
+		} else {
+			status = resp.Status
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		tflog.Debug(ctx, "Retrying Proxmox VE API request", map[string]interface{}{
+			"method":  req.Method,
+			"path":    req.URL.Path,
+			"status":  status,
+			"attempt": attempt + 1,
+		})
+
+		if err := t.policy.sleep(ctx, attempt); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func isRetryable(req *http.Request, resp *http.Response, err error) bool {
+	if err != nil {
+		// A certificate which cannot be verified will not be once more.
+		return req.Context().Err() == nil && req.Method == http.MethodGet && !isTLSError(err)
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return req.Method == http.MethodGet || req.Method == http.MethodDelete
+	case http.StatusInternalServerError:
+		message := strings.TrimPrefix(resp.Status, strconv.Itoa(resp.StatusCode)+" ")
+		// The lock is taken before anything is changed.
+		return newAPIError(resp.StatusCode, message, nil).Kind == apiErrorLocked
+	}
+	return false
+}
+
+// rewindBody prepares req to be sent again, reporting whether it is possible.
+func rewindBody(req *http.Request) bool {
+	if req.Body == nil || req.Body == http.NoBody {
+		return true
+	}
+	if req.GetBody == nil {
+		return false
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return false
+	}
+	req.Body = body
+	return true
+}
+
+// contextTransport binds the requests it sends to ctx, as the API client does
+// not take a context itself.
+type contextTransport struct {
+	ctx  context.Context
+	next http.RoundTripper
+}
+
+func (t *contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.next.RoundTrip(req.WithContext(t.ctx))
+}
+
+// withRetries makes the requests sent by c retry transient failures.
+func withRetries(c *proxmox.Client, policy retryPolicy) *proxmox.Client {
+	c.HTTPClient.Transport = &retryTransport{next: transport(c.HTTPClient), policy: policy}
+	return c
+}
+
+// withContext returns a copy of c whose requests are cancelled when ctx is
+// done. Every API call made on behalf of a Terraform operation should go
+// through it, so that retries stop once Terraform gives up.
+func withContext(ctx context.Context, c *proxmox.Client) *proxmox.Client {
+	if c == nil {
+		return nil
+	}
+	httpClient := *c.HTTPClient
+	httpClient.Transport = &contextTransport{ctx: ctx, next: transport(c.HTTPClient)}
+	client := *c
+	client.HTTPClient = &httpClient
+	return &client
+}
+
+// clientRetryPolicy returns the retry policy c was configured with.
+func clientRetryPolicy(c *proxmox.Client) retryPolicy {
+	if c != nil {
+		if t, ok := c.HTTPClient.Transport.(*retryTransport); ok {
+			return t.policy
+		}
+	}
+	return defaultRetryPolicy
+}
+
+func transport(c *http.Client) http.RoundTripper {
+	if c.Transport == nil {
+		return http.DefaultTransport
+	}
+	return c.Transport
+}
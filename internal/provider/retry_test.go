@@ -0,0 +1,112 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := retryPolicy{BackoffMin: time.Second, BackoffMax: 5 * time.Second}
+	assert.Equal(t, 1*time.Second, p.backoff(0))
+	assert.Equal(t, 2*time.Second, p.backoff(1))
+	assert.Equal(t, 4*time.Second, p.backoff(2))
+	assert.Equal(t, 5*time.Second, p.backoff(3))
+	assert.Equal(t, 5*time.Second, p.backoff(100))
+}
+
+// testRetryServer replies with the given status lines in order, then 200.
+func testRetryServer(t *testing.T, statuses ...string) (*httptest.Server, *int32) {
+	var calls int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(&calls, 1))
+		if n > len(statuses) {
+			_, _ = w.Write([]byte(`{"data":null}`))
+			return
+		}
+		conn, buf, _ := w.(http.Hijacker).Hijack()
+		defer conn.Close()
+		_, _ = buf.WriteString("HTTP/1.1 " + statuses[n-1] + "\r\nContent-Length: 0\r\nConnection: close\r\n\r\n")
+		_ = buf.Flush()
+	}))
+	t.Cleanup(s.Close)
+	return s, &calls
+}
+
+func TestRetryTransport(t *testing.T) {
+	policy := retryPolicy{MaxRetries: 2, BackoffMin: time.Millisecond, BackoffMax: time.Millisecond}
+	client := &http.Client{Transport: &retryTransport{next: http.DefaultTransport, policy: policy}}
+
+	for _, tc := range []struct {
+		name     string
+		method   string
+		statuses []string
+		status   int
+		calls    int32
+	}{
+		{"unavailable", http.MethodGet, []string{"503 Service Unavailable"}, http.StatusOK, 2},
+		{"bad gateway on delete", http.MethodDelete, []string{"502 Bad Gateway"}, http.StatusOK, 2},
+		// The node may have applied the request already.
+		{"bad gateway on create", http.MethodPost, []string{"502 Bad Gateway"}, http.StatusBadGateway, 1},
+		{"gateway timeout on update", http.MethodPut, []string{"504 Gateway Timeout"}, http.StatusGatewayTimeout, 1},
+		{"cfs-lock", http.MethodPost, []string{"500 cfs-lock 'file-storage_cfg' error: got lock request timeout"}, http.StatusOK, 2},
+		{"gives up", http.MethodGet, []string{"503 Service Unavailable", "503 Service Unavailable", "503 Service Unavailable"}, http.StatusServiceUnavailable, 3},
+		{"not retryable", http.MethodGet, []string{"500 storage 'foo' does not exist"}, http.StatusInternalServerError, 1},
+		{"validation", http.MethodPost, []string{"400 Parameter verification failed."}, http.StatusBadRequest, 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s, calls := testRetryServer(t, tc.statuses...)
+			req, err := http.NewRequest(tc.method, s.URL, nil)
+			require.NoError(t, err)
+			resp, err := client.Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, tc.status, resp.StatusCode)
+			assert.Equal(t, tc.calls, atomic.LoadInt32(calls))
+		})
+	}
+}
+
+func TestRetryTransportContext(t *testing.T) {
+	policy := retryPolicy{MaxRetries: 10, BackoffMin: time.Hour, BackoffMax: time.Hour}
+	s, calls := testRetryServer(t, "503 Service Unavailable")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	client := &http.Client{Transport: &contextTransport{ctx: ctx, next: &retryTransport{next: http.DefaultTransport, policy: policy}}}
+
+	_, err := client.Get(s.URL)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+}
+
+func TestRetryPolicyPoll(t *testing.T) {
+	policy := retryPolicy{BackoffMin: time.Millisecond, BackoffMax: 5 * time.Millisecond}
+
+	calls := 0
+	err := policy.poll(context.Background(), func() (bool, error) {
+		calls++
+		return calls == 3, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+
+	calls = 0
+	err = policy.poll(context.Background(), func() (bool, error) {
+		calls++
+		return false, assert.AnError
+	})
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.Equal(t, 1, calls)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err = policy.poll(ctx, func() (bool, error) { return false, nil })
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,179 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// sshHandshakeTimeout bounds the connection to the SSH jump host.
+const sshHandshakeTimeout = 30 * time.Second
+
+// sshTunnelOptions configures the SSH jump host the API servers are reached
+// through.
+type sshTunnelOptions struct {
+	// Host is the address of the jump host, with an optional port.
+	Host string
+	User string
+
+	// PrivateKey is a PEM-encoded private key. The keys of the SSH agent
+	// listening on SSH_AUTH_SOCK are used if empty.
+	PrivateKey string
+
+	// HostKey is the public key of the jump host, in authorized_keys format.
+	// The user's known_hosts file is used if empty.
+	HostKey string
+}
+
+// sshTunnel opens connections to the API servers from an SSH jump host. The
+// SSH connection is opened on first use, and again whenever it drops.
+type sshTunnel struct {
+	addr   string
+	config *ssh.ClientConfig
+
+	// agentSocket is the SSH agent authenticating the connection, if any.
+	agentSocket string
+
+	mu     sync.Mutex
+	client *ssh.Client
+}
+
+func newSSHTunnel(o sshTunnelOptions) (*sshTunnel, error) {
+	if o.User == "" {
+		return nil, errors.New("the user of the SSH jump host cannot be empty")
+	}
+	t := &sshTunnel{
+		addr:   o.Host,
+		config: &ssh.ClientConfig{User: o.User, Timeout: sshHandshakeTimeout},
+	}
+	if _, _, err := net.SplitHostPort(o.Host); err != nil {
+		t.addr = net.JoinHostPort(o.Host, "22")
+	}
+
+	if o.PrivateKey != "" {
+		signer, err := ssh.ParsePrivateKey([]byte(o.PrivateKey))
+		var passphraseErr *ssh.PassphraseMissingError
+		if errors.As(err, &passphraseErr) {
+			return nil, errors.New("the SSH private key is protected by a passphrase, load it into an SSH agent instead")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse the SSH private key: %w", err)
+		}
+		t.config.Auth = []ssh.AuthMethod{ssh.PublicKeys(signer)}
+	} else {
+		t.agentSocket = os.Getenv("SSH_AUTH_SOCK")
+		if t.agentSocket == "" {
+			return nil, errors.New("either an SSH private key or an SSH agent (SSH_AUTH_SOCK) is required")
+		}
+	}
+
+	if o.HostKey != "" {
+		hostKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(o.HostKey))
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse the host key of the SSH jump host: %w", err)
+		}
+		t.config.HostKeyCallback = ssh.FixedHostKey(hostKey)
+	} else {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		callback, err := knownhosts.New(filepath.Join(home, ".ssh", "known_hosts"))
+		if err != nil {
+			return nil, fmt.Errorf("the host key of the SSH jump host is needed unless it is in known_hosts: %w", err)
+		}
+		t.config.HostKeyCallback = callback
+	}
+	return t, nil
+}
+
+// DialContext opens a connection to addr from the jump host. Its errors are
+// dial errors, so that the endpoints behind the tunnel still fail over.
+func (t *sshTunnel) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	client, err := t.sshClient(ctx)
+	if err != nil {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: err}
+	}
+	conn, err := client.Dial(network, addr)
+	var channelErr *ssh.OpenChannelError
+	if err != nil && !errors.As(err, &channelErr) {
+		// The SSH connection itself failed, so open another one.
+		t.reset(client)
+		if client, err = t.sshClient(ctx); err == nil {
+			conn, err = client.Dial(network, addr)
+		}
+	}
+	if err != nil {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: err}
+	}
+	return conn, nil
+}
+
+// sshClient returns the connection to the jump host, opening it if needed.
+func (t *sshTunnel) sshClient(ctx context.Context) (*ssh.Client, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.client != nil {
+		return t.client, nil
+	}
+
+	client, err := t.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	t.client = client
+	go func() {
+		client.Wait()
+		t.reset(client)
+	}()
+	return client, nil
+}
+
+func (t *sshTunnel) connect(ctx context.Context) (*ssh.Client, error) {
+	config := *t.config
+	if t.agentSocket != "" {
+		agentConn, err := net.Dial("unix", t.agentSocket)
+		if err != nil {
+			return nil, fmt.Errorf("unable to connect to the SSH agent: %w", err)
+		}
+		defer agentConn.Close()
+		config.Auth = []ssh.AuthMethod{ssh.PublicKeysCallback(agent.NewClient(agentConn).Signers)}
+	}
+
+	dialer := net.Dialer{Timeout: config.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", t.addr)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to SSH jump host %s: %w", t.addr, err)
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok || time.Until(deadline) > config.Timeout {
+		deadline = time.Now().Add(config.Timeout)
+	}
+	conn.SetDeadline(deadline)
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, t.addr, &config)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("unable to connect to SSH jump host %s: %w", t.addr, err)
+	}
+	conn.SetDeadline(time.Time{})
+	return ssh.NewClient(sshConn, chans, reqs), nil
+}
+
+// reset forgets client if it is still the connection in use, and closes it.
+func (t *sshTunnel) reset(client *ssh.Client) {
+	t.mu.Lock()
+	if t.client == client {
+		t.client = nil
+	}
+	t.mu.Unlock()
+	client.Close()
+}
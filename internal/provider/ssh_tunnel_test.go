@@ -0,0 +1,183 @@
+package provider
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"terraform-provider-proxmoxve/internal/pvemock"
+
+	"github.com/c10l/proxmoxve-client-go/api/cluster/firewall/aliases"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// testSSHKey returns a new private key, PEM-encoded.
+func testSSHKey(t *testing.T) (ed25519.PrivateKey, string) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	return key, string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+}
+
+// testSSHServer is a jump host accepting the user "terraform" with
+// authorizedKey, and forwarding TCP connections. It returns its address and
+// host key, and counts the connections it forwarded.
+func testSSHServer(t *testing.T, authorizedKey ed25519.PrivateKey) (string, string, *int32) {
+	var forwarded int32
+	hostKey, _ := testSSHKey(t)
+	hostSigner, err := ssh.NewSignerFromKey(hostKey)
+	require.NoError(t, err)
+	authorized, err := ssh.NewPublicKey(authorizedKey.Public())
+	require.NoError(t, err)
+
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if conn.User() == "terraform" && string(key.Marshal()) == string(authorized.Marshal()) {
+				return nil, nil
+			}
+			return nil, ssh.ErrNoAuth
+		},
+	}
+	config.AddHostKey(hostSigner)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	handle := func(conn net.Conn) {
+		_, chans, reqs, err := ssh.NewServerConn(conn, config)
+		if err != nil {
+			return
+		}
+		go ssh.DiscardRequests(reqs)
+		for newChannel := range chans {
+			var target struct {
+				Host     string
+				Port     uint32
+				OrigHost string
+				OrigPort uint32
+			}
+			if newChannel.ChannelType() != "direct-tcpip" || ssh.Unmarshal(newChannel.ExtraData(), &target) != nil {
+				newChannel.Reject(ssh.UnknownChannelType, "only direct-tcpip is supported")
+				continue
+			}
+			upstream, err := net.Dial("tcp", net.JoinHostPort(target.Host, strconv.Itoa(int(target.Port))))
+			if err != nil {
+				newChannel.Reject(ssh.ConnectionFailed, err.Error())
+				continue
+			}
+			channel, requests, err := newChannel.Accept()
+			if err != nil {
+				upstream.Close()
+				continue
+			}
+			go ssh.DiscardRequests(requests)
+			atomic.AddInt32(&forwarded, 1)
+			go testPipe(upstream, testChannelConn{channel})
+		}
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go handle(conn)
+		}
+	}()
+
+	return l.Addr().String(), string(ssh.MarshalAuthorizedKey(hostSigner.PublicKey())), &forwarded
+}
+
+// testChannelConn lets testPipe copy from and to an SSH channel.
+type testChannelConn struct {
+	ssh.Channel
+}
+
+func (testChannelConn) LocalAddr() net.Addr  { return nil }
+func (testChannelConn) RemoteAddr() net.Addr { return nil }
+
+func (testChannelConn) SetDeadline(t time.Time) error      { return nil }
+func (testChannelConn) SetReadDeadline(t time.Time) error  { return nil }
+func (testChannelConn) SetWriteDeadline(t time.Time) error { return nil }
+
+// testTunnelRequest sends a request to s through tunnel.
+func testTunnelRequest(t *testing.T, s *pvemock.Server, tunnel *sshTunnel) error {
+	httpTransport := newHTTPTransport(&tls.Config{InsecureSkipVerify: true}, nil, tunnel)
+	t.Cleanup(httpTransport.CloseIdleConnections)
+	_, err := aliases.GetRequest{Client: testTransportClient(t, s, httpTransport)}.Get()
+	return err
+}
+
+func TestSSHTunnel(t *testing.T) {
+	s := pvemock.New()
+	t.Cleanup(s.Close)
+	key, keyPEM := testSSHKey(t)
+	addr, hostKey, forwarded := testSSHServer(t, key)
+
+	tunnel, err := newSSHTunnel(sshTunnelOptions{Host: addr, User: "terraform", PrivateKey: keyPEM, HostKey: hostKey})
+	require.NoError(t, err)
+	require.NoError(t, testTunnelRequest(t, s, tunnel))
+	assert.Equal(t, int32(1), atomic.LoadInt32(forwarded))
+
+	// A dropped SSH connection is opened again.
+	tunnel.reset(tunnel.client)
+	require.NoError(t, testTunnelRequest(t, s, tunnel))
+	assert.Equal(t, int32(2), atomic.LoadInt32(forwarded))
+
+	// The host key of the jump host is verified.
+	_, otherHostKey, _ := testSSHServer(t, key)
+	tunnel, err = newSSHTunnel(sshTunnelOptions{Host: addr, User: "terraform", PrivateKey: keyPEM, HostKey: otherHostKey})
+	require.NoError(t, err)
+	assert.ErrorContains(t, testTunnelRequest(t, s, tunnel), "host key mismatch")
+
+	_, otherKeyPEM := testSSHKey(t)
+	tunnel, err = newSSHTunnel(sshTunnelOptions{Host: addr, User: "terraform", PrivateKey: otherKeyPEM, HostKey: hostKey})
+	require.NoError(t, err)
+	assert.ErrorContains(t, testTunnelRequest(t, s, tunnel), "unable to authenticate")
+}
+
+func TestSSHTunnelAgent(t *testing.T) {
+	s := pvemock.New()
+	t.Cleanup(s.Close)
+	key, _ := testSSHKey(t)
+	addr, hostKey, forwarded := testSSHServer(t, key)
+
+	keyring := agent.NewKeyring()
+	require.NoError(t, keyring.Add(agent.AddedKey{PrivateKey: key}))
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	l, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go agent.ServeAgent(keyring, conn)
+		}
+	}()
+
+	t.Setenv("SSH_AUTH_SOCK", "")
+	_, err = newSSHTunnel(sshTunnelOptions{Host: addr, User: "terraform", HostKey: hostKey})
+	assert.ErrorContains(t, err, "SSH agent")
+
+	t.Setenv("SSH_AUTH_SOCK", socket)
+	tunnel, err := newSSHTunnel(sshTunnelOptions{Host: addr, User: "terraform", HostKey: hostKey})
+	require.NoError(t, err)
+	require.NoError(t, testTunnelRequest(t, s, tunnel))
+	assert.Equal(t, int32(1), atomic.LoadInt32(forwarded))
+}
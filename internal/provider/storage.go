@@ -0,0 +1,286 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	proxmox "github.com/c10l/proxmoxve-client-go/api"
+	"github.com/c10l/proxmoxve-client-go/api/storage"
+	pvetypes "github.com/c10l/proxmoxve-client-go/helpers/types"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// readStorage returns the configuration of the storage called name. It is
+// found in the list of all storages, which the client caches, so that
+// refreshing many storages reads the list once.
+func readStorage(ctx context.Context, client *proxmox.Client, name string) (*storage.ItemGetResponse, error) {
+	item, _, err := readStorageOptions(ctx, client, name)
+	return item, err
+}
+
+// readStorageOptions is readStorage, also returning all the options of the
+// storage, including those of its type which storage.ItemGetResponse lacks.
+func readStorageOptions(ctx context.Context, client *proxmox.Client, name string) (*storage.ItemGetResponse, storageOptions, error) {
+	body, err := storage.GetRequest{Client: withContext(ctx, client)}.GetAll()
+	if err != nil {
+		return nil, nil, err
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(body, &items); err != nil {
+		return nil, nil, err
+	}
+	for _, raw := range items {
+		var item storage.ItemGetResponse
+		if err := json.Unmarshal(raw, &item); err != nil {
+			return nil, nil, err
+		}
+		if item.Storage != name {
+			continue
+		}
+		var options storageOptions
+		if err := json.Unmarshal(raw, &options); err != nil {
+			return nil, nil, err
+		}
+		return &item, options, nil
+	}
+	return nil, nil, &apiError{Kind: apiErrorNotFound, StatusCode: http.StatusInternalServerError, Message: fmt.Sprintf("storage '%s' does not exist", name)}
+}
+
+// storageOptions are the options of a storage as returned by the API, by name.
+// Absent options read as zero values, like those of storage.ItemGetResponse.
+type storageOptions map[string]json.RawMessage
+
+// string returns the string option called name.
+func (o storageOptions) string(name string) string {
+	var s string
+	_ = json.Unmarshal(o[name], &s)
+	return s
+}
+
+// bool returns the boolean option called name, returned as 0 or 1.
+func (o storageOptions) bool(name string) bool {
+	var b pvetypes.PVEBool
+	_ = b.UnmarshalJSON(bytes.Trim(o[name], `"`))
+	return bool(b)
+}
+
+// int64 returns the integer option called name, or fallback if it is not set.
+func (o storageOptions) int64(name string, fallback int64) int64 {
+	n := fallback
+	if raw, ok := o[name]; ok {
+		_ = json.Unmarshal(bytes.Trim(raw, `"`), &n)
+	}
+	return n
+}
+
+// storageResponse is the response to the creation or the update of a storage.
+type storageResponse struct {
+	Storage string `json:"storage"`
+	Type    string `json:"type"`
+	// Config holds the options generated by Proxmox VE, e.g. the encryption
+	// key of a pbs storage.
+	Config storageOptions `json:"config,omitempty"`
+}
+
+// parseStorageResponse parses the body returned by the creation or the update
+// of a storage.
+func parseStorageResponse(body []byte, err error) (*storageResponse, error) {
+	if err != nil {
+		return nil, err
+	}
+	resp := new(storageResponse)
+	return resp, json.Unmarshal(body, resp)
+}
+
+// storagePostRequest creates a storage with the options of its type which
+// storage.PostRequest lacks.
+type storagePostRequest struct {
+	storage.PostRequest
+
+	// Params are sent along with those of PostRequest.
+	Params url.Values
+}
+
+func (p storagePostRequest) Post() (*storageResponse, error) {
+	return parseStorageResponse(p.PostItem())
+}
+
+func (p storagePostRequest) PostItem() ([]byte, error) {
+	return p.Client.PostItem(p, "/storage")
+}
+
+func (p storagePostRequest) ParseParams(apiURL *url.URL) error {
+	if err := p.PostRequest.ParseParams(apiURL); err != nil {
+		return err
+	}
+	apiURL.RawQuery = withParams(apiURL.Query(), p.Params).Encode()
+	return nil
+}
+
+// storagePutRequest updates a storage with the options of its type which
+// storage.ItemPutRequest lacks.
+type storagePutRequest struct {
+	storage.ItemPutRequest
+
+	// Params are sent along with those of ItemPutRequest.
+	Params url.Values
+}
+
+func (p storagePutRequest) Put() (*storageResponse, error) {
+	return parseStorageResponse(p.PutItem())
+}
+
+func (p storagePutRequest) PutItem() ([]byte, error) {
+	return p.Client.PutItem(p, "/storage", p.Storage)
+}
+
+func (p storagePutRequest) ParseParams(apiURL *url.URL) error {
+	// ItemPutRequest refuses to send no parameters, which is fine if Params
+	// has some.
+	if err := p.ItemPutRequest.ParseParams(apiURL); err != nil && len(p.Params) == 0 {
+		return err
+	}
+	apiURL.RawQuery = withParams(apiURL.Query(), p.Params).Encode()
+	return nil
+}
+
+// withParams returns params with those of extra added.
+func withParams(params, extra url.Values) url.Values {
+	for k, v := range extra {
+		params[k] = v
+	}
+	return params
+}
+
+// storageContentValidator checks that the content types of a storage are
+// supported by its type, rather than having the API reject them.
+type storageContentValidator struct {
+	storageType string
+	supported   []string
+}
+
+func (v storageContentValidator) Description(ctx context.Context) string {
+	quoted := make([]string, len(v.supported))
+	for i, c := range v.supported {
+		quoted[i] = "`" + c + "`"
+	}
+	if len(quoted) == 1 {
+		return "value must be " + quoted[0]
+	}
+	return "values must be among " + strings.Join(quoted[:len(quoted)-1], ", ") + " and " + quoted[len(quoted)-1]
+}
+
+func (v storageContentValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v storageContentValidator) ValidateSet(ctx context.Context, req validator.SetRequest, resp *validator.SetResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+	for _, element := range req.ConfigValue.Elements() {
+		content, ok := element.(types.String)
+		if !ok || content.IsNull() || content.IsUnknown() {
+			continue
+		}
+		supported := false
+		for _, c := range v.supported {
+			supported = supported || content.ValueString() == c
+		}
+		if !supported {
+			resp.Diagnostics.AddAttributeError(
+				req.Path,
+				"Unsupported content type",
+				fmt.Sprintf("%q is not supported by %s storages: %s", content.ValueString(), v.storageType, v.Description(ctx)),
+			)
+		}
+	}
+}
+
+// storageSecretHashKey returns the key of the private state holding the
+// SHA-256 hash of the value last set to the secret option param of a storage,
+// e.g. its password. The API never returns secrets, so the hash tells whether
+// the configured value needs to be sent.
+func storageSecretHashKey(param string) string {
+	return param + "_sha256"
+}
+
+// privateState is the private state of a resource, e.g. resource.UpdateRequest.Private.
+type privateState interface {
+	GetKey(ctx context.Context, key string) ([]byte, diag.Diagnostics)
+	SetKey(ctx context.Context, key string, value []byte) diag.Diagnostics
+}
+
+// storageSecretHash returns the hash of value kept in the private state, as a
+// JSON string: empty if value is null.
+func storageSecretHash(value types.String) []byte {
+	hash := ""
+	if !value.IsNull() {
+		sum := sha256.Sum256([]byte(value.ValueString()))
+		hash = hex.EncodeToString(sum[:])
+	}
+	encoded, _ := json.Marshal(hash)
+	return encoded
+}
+
+// storageSecretChanged reports whether value differs from the one set to the
+// secret option param, whose hash is held by private.
+func storageSecretChanged(ctx context.Context, private privateState, param string, value types.String, diags *diag.Diagnostics) bool {
+	previous, getDiags := private.GetKey(ctx, storageSecretHashKey(param))
+	diags.Append(getDiags...)
+	if previous == nil {
+		// Created before the hash was kept.
+		return !value.IsNull()
+	}
+	return !bytes.Equal(previous, storageSecretHash(value))
+}
+
+// setChangedStorageSecrets adds to params the secret options, by API
+// parameter, which changed since last set. Those now null are deleted.
+func setChangedStorageSecrets(ctx context.Context, private privateState, params url.Values, secrets map[string]types.String, diags *diag.Diagnostics) {
+	var deleted []string
+	for param, value := range secrets {
+		if !storageSecretChanged(ctx, private, param, value, diags) {
+			continue
+		}
+		if value.IsNull() {
+			deleted = append(deleted, param)
+		} else {
+			params.Set(param, value.ValueString())
+		}
+	}
+	if len(deleted) > 0 {
+		sort.Strings(deleted)
+		params.Set("delete", strings.Join(deleted, ","))
+	}
+}
+
+// keepStorageSecretHashes records in private the hashes of the secret options
+// set, by API parameter.
+func keepStorageSecretHashes(ctx context.Context, private privateState, secrets map[string]types.String) diag.Diagnostics {
+	var diags diag.Diagnostics
+	for param, value := range secrets {
+		diags.Append(private.SetKey(ctx, storageSecretHashKey(param), storageSecretHash(value))...)
+	}
+	return diags
+}
+
+// storagePrivileges are the privileges needed to manage the storage called
+// name: Datastore.Allocate on /storage to create it, or on the storage itself
+// otherwise.
+func storagePrivileges(name string, create bool) []privilege {
+	if create || name == "" {
+		return []privilege{{Path: "/storage", Name: "Datastore.Allocate"}}
+	}
+	return []privilege{{Path: "/storage/" + name, Name: "Datastore.Allocate"}}
+}
@@ -0,0 +1,110 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"testing"
+
+	"github.com/c10l/proxmoxve-client-go/api/storage"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorageOptions(t *testing.T) {
+	var options storageOptions
+	require.NoError(t, json.Unmarshal([]byte(`{"pool":"rpool/data","sparse":1,"tagged_only":"1","saferemove":0,"port":8008}`), &options))
+
+	assert.Equal(t, "rpool/data", options.string("pool"))
+	assert.Equal(t, "", options.string("mountpoint"))
+	assert.True(t, options.bool("sparse"))
+	assert.True(t, options.bool("tagged_only"))
+	assert.False(t, options.bool("saferemove"))
+	assert.False(t, options.bool("shared"))
+	assert.Equal(t, int64(8008), options.int64("port", 8007))
+	assert.Equal(t, int64(0), options.int64("saferemove", 8007))
+	assert.Equal(t, int64(8007), options.int64("blocksize", 8007))
+}
+
+func TestStorageContentValidator(t *testing.T) {
+	v := storageContentValidator{storage.TypeZFSPool, []string{storage.ContentImages, storage.ContentRootDir}}
+	validate := func(content ...string) *validator.SetResponse {
+		elements := make([]attr.Value, len(content))
+		for i, c := range content {
+			elements[i] = types.StringValue(c)
+		}
+		resp := &validator.SetResponse{}
+		v.ValidateSet(context.Background(), validator.SetRequest{
+			Path:        path.Root("content"),
+			ConfigValue: types.SetValueMust(types.StringType, elements),
+		}, resp)
+		return resp
+	}
+
+	assert.False(t, validate("images", "rootdir").Diagnostics.HasError())
+	assert.False(t, validate().Diagnostics.HasError())
+
+	resp := validate("images", "backup", "iso")
+	require.Len(t, resp.Diagnostics, 2)
+	assert.Equal(t, "Unsupported content type", resp.Diagnostics[0].Summary())
+	assert.Equal(t, "\"backup\" is not supported by zfspool storages: values must be among `images` and `rootdir`", resp.Diagnostics[0].Detail())
+}
+
+// testPrivateState is an in-memory private state.
+type testPrivateState map[string][]byte
+
+func (p testPrivateState) GetKey(ctx context.Context, key string) ([]byte, diag.Diagnostics) {
+	return p[key], nil
+}
+
+func (p testPrivateState) SetKey(ctx context.Context, key string, value []byte) diag.Diagnostics {
+	p[key] = value
+	return nil
+}
+
+func TestStorageSecrets(t *testing.T) {
+	ctx := context.Background()
+	var diags diag.Diagnostics
+	changed := func(private privateState, value types.String) bool {
+		return storageSecretChanged(ctx, private, "password", value, &diags)
+	}
+
+	// Without a hash, e.g. after an import, any password is sent.
+	private := testPrivateState{}
+	assert.False(t, changed(private, types.StringNull()))
+	assert.True(t, changed(private, types.StringValue("secret")))
+
+	keepStorageSecretHashes(ctx, private, map[string]types.String{"password": types.StringValue("secret")})
+	assert.NotContains(t, string(private["password_sha256"]), "secret")
+	assert.False(t, changed(private, types.StringValue("secret")))
+	assert.True(t, changed(private, types.StringValue("other")))
+	assert.True(t, changed(private, types.StringNull()))
+
+	keepStorageSecretHashes(ctx, private, map[string]types.String{"password": types.StringNull()})
+	assert.True(t, json.Valid(private["password_sha256"]))
+	assert.False(t, changed(private, types.StringNull()))
+	assert.True(t, changed(private, types.StringValue("")))
+
+	// Only the secrets which changed are sent, and those removed are deleted.
+	keepStorageSecretHashes(ctx, private, map[string]types.String{
+		"password":       types.StringValue("secret"),
+		"encryption-key": types.StringValue("key"),
+		"master-pubkey":  types.StringValue("pubkey"),
+	})
+	params := url.Values{}
+	setChangedStorageSecrets(ctx, private, params, map[string]types.String{
+		"password":       types.StringValue("other"),
+		"encryption-key": types.StringNull(),
+		"master-pubkey":  types.StringNull(),
+	}, &diags)
+	assert.Equal(t, url.Values{"password": {"other"}, "delete": {"encryption-key,master-pubkey"}}, params)
+	params = url.Values{}
+	setChangedStorageSecrets(ctx, private, params, map[string]types.String{"password": types.StringValue("secret")}, &diags)
+	assert.Empty(t, params)
+	assert.False(t, diags.HasError())
+}
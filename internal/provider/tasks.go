@@ -0,0 +1,128 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	proxmox "github.com/c10l/proxmoxve-client-go/api"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+const (
+	// taskPollIntervalMax caps the backoff between two polls of a task status,
+	// so long running tasks are not noticed much later than they finish.
+	taskPollIntervalMax = 5 * time.Second
+
+	// taskErrorLogLines is the number of task log lines reported on failure.
+	taskErrorLogLines = 10
+)
+
+// taskStatus is returned by /nodes/{node}/tasks/{upid}/status.
+type taskStatus struct {
+	Status     string `json:"status"`
+	ExitStatus string `json:"exitstatus"`
+}
+
+// taskLogLine is an item of /nodes/{node}/tasks/{upid}/log.
+type taskLogLine struct {
+	N int    `json:"n"`
+	T string `json:"t"`
+}
+
+// taskError is returned by waitForTask when a task did not finish successfully.
+type taskError struct {
+	UPID       string
+	ExitStatus string
+	Log        []string
+}
+
+func (e *taskError) Error() string {
+	msg := fmt.Sprintf("task %s failed: %s", e.UPID, e.ExitStatus)
+	if len(e.Log) > 0 {
+		msg += "\n\nLast lines of the task log:\n" + strings.Join(e.Log, "\n")
+	}
+	return msg
+}
+
+// upidNode returns the node a task runs on, from its UPID, e.g.
+// `UPID:pve:000A8F3C:0390E4A4:63E8E2B6:acmeregister:default:root@pam:`.
+func upidNode(upid string) (string, error) {
+	parts := strings.Split(upid, ":")
+	if len(parts) < 9 || parts[0] != "UPID" || parts[1] == "" {
+		return "", fmt.Errorf("invalid task identifier %q", upid)
+	}
+	return parts[1], nil
+}
+
+// waitForTask waits until the task identified by upid finishes, logging its
+// output to tflog as it progresses. It returns a *taskError carrying the exit
+// status and the last lines of the log if the task failed. Waiting is bounded
+// by the deadline of ctx.
+func waitForTask(ctx context.Context, client *proxmox.Client, upid string) error {
+	node, err := upidNode(upid)
+	if err != nil {
+		return err
+	}
+	taskURL := client.APIurl
+	taskURL.Path += fmt.Sprintf("/nodes/%s/tasks/%s", node, upid)
+	c := withContext(ctx, client)
+
+	policy := clientRetryPolicy(client)
+	if policy.BackoffMax > taskPollIntervalMax {
+		policy.BackoffMax = taskPollIntervalMax
+	}
+	if policy.BackoffMin > policy.BackoffMax {
+		policy.BackoffMin = policy.BackoffMax
+	}
+
+	var lastLines []string
+	logStart := 0
+	return policy.poll(ctx, func() (bool, error) {
+		var status taskStatus
+		statusURL := taskURL
+		statusURL.Path += "/status"
+		if err := getJSON(c, &statusURL, &status); err != nil {
+			return false, err
+		}
+
+		// Fetch the log after the status, so no line is missed once the task has stopped.
+		var lines []taskLogLine
+		logURL := taskURL
+		logURL.Path += "/log"
+		logURL.RawQuery = url.Values{"start": {strconv.Itoa(logStart)}, "limit": {"500"}}.Encode()
+		if err := getJSON(c, &logURL, &lines); err != nil {
+			return false, err
+		}
+		for _, line := range lines {
+			tflog.Info(ctx, line.T, map[string]interface{}{"upid": upid})
+			lastLines = append(lastLines, line.T)
+		}
+		logStart += len(lines)
+		if len(lastLines) > taskErrorLogLines {
+			lastLines = lastLines[len(lastLines)-taskErrorLogLines:]
+		}
+
+		if status.Status == "running" {
+			return false, nil
+		}
+		// Tasks which logged warnings finish with e.g. "WARNINGS: 2".
+		if status.ExitStatus != "OK" && !strings.HasPrefix(status.ExitStatus, "WARNINGS") {
+			return true, &taskError{UPID: upid, ExitStatus: status.ExitStatus, Log: lastLines}
+		}
+		return true, nil
+	})
+}
+
+// getJSON decodes the data returned by the API for u into v.
+func getJSON(client *proxmox.Client, u *url.URL, v any) error {
+	data, err := client.Get(u)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,85 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	proxmox "github.com/c10l/proxmoxve-client-go/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testUPID = "UPID:pve:000A8F3C:0390E4A4:63E8E2B6:aptupdate::root@pam:"
+
+// testTaskServer serves a task which logs a line on every status poll and
+// stops with exitStatus after the given number of polls.
+func testTaskServer(t *testing.T, polls int, exitStatus string) *proxmox.Client {
+	var log []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prefix := "/api2/json/nodes/pve/tasks/" + testUPID
+		var data any
+		switch r.URL.Path {
+		case prefix + "/status":
+			log = append(log, fmt.Sprintf("line %d", len(log)+1))
+			status := map[string]string{"status": "running"}
+			if len(log) >= polls {
+				status = map[string]string{"status": "stopped", "exitstatus": exitStatus}
+			}
+			data = status
+		case prefix + "/log":
+			start, _ := strconv.Atoi(r.URL.Query().Get("start"))
+			lines := []taskLogLine{}
+			for i := start; i < len(log); i++ {
+				lines = append(lines, taskLogLine{N: i + 1, T: log[i]})
+			}
+			data = lines
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": data})
+	}))
+	t.Cleanup(s.Close)
+
+	client, err := proxmox.NewAPITokenClient(s.URL, "root@pam!test", "secret", true)
+	require.NoError(t, err)
+	return withRetries(client, retryPolicy{BackoffMin: time.Millisecond, BackoffMax: time.Millisecond})
+}
+
+func TestUPIDNode(t *testing.T) {
+	node, err := upidNode(testUPID)
+	assert.NoError(t, err)
+	assert.Equal(t, "pve", node)
+
+	_, err = upidNode("not a upid")
+	assert.Error(t, err)
+}
+
+func TestWaitForTask(t *testing.T) {
+	client := testTaskServer(t, 3, "OK")
+	assert.NoError(t, waitForTask(context.Background(), client, testUPID))
+
+	client = testTaskServer(t, 2, "WARNINGS: 1")
+	assert.NoError(t, waitForTask(context.Background(), client, testUPID))
+
+	client = testTaskServer(t, 15, "command 'apt-get update' failed: exit code 100")
+	err := waitForTask(context.Background(), client, testUPID)
+	var taskErr *taskError
+	require.ErrorAs(t, err, &taskErr)
+	assert.Equal(t, "command 'apt-get update' failed: exit code 100", taskErr.ExitStatus)
+	assert.Len(t, taskErr.Log, taskErrorLogLines)
+	assert.Equal(t, "line 15", taskErr.Log[len(taskErr.Log)-1])
+	assert.True(t, strings.HasPrefix(err.Error(), "task "+testUPID+" failed"))
+
+	client = testTaskServer(t, 1000, "OK")
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, waitForTask(ctx, client, testUPID), context.DeadlineExceeded)
+}
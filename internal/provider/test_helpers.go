@@ -2,7 +2,12 @@ package provider
 
 import (
 	"fmt"
+	"os"
 	"regexp"
+	"strconv"
+	"testing"
+
+	proxmox "github.com/c10l/proxmoxve-client-go/api"
 )
 
 func testAccRegexpMatch(regex string) func(string) error {
@@ -17,3 +22,19 @@ func testAccRegexpMatch(regex string) func(string) error {
 		return fmt.Errorf("expected %s, got %s", regex, v)
 	}
 }
+
+// testAccDeleteOutOfBand returns a TestStep.PreConfig function that calls
+// deleteFunc with an API client, simulating an object being removed outside of
+// Terraform, e.g. through the web UI.
+func testAccDeleteOutOfBand(t *testing.T, deleteFunc func(*proxmox.Client) error) func() {
+	return func() {
+		insecure, _ := strconv.ParseBool(os.Getenv("PROXMOXVE_TLS_INSECURE"))
+		client, err := proxmox.NewAPITokenClient(os.Getenv("PROXMOXVE_BASE_URL"), os.Getenv("PROXMOXVE_TOKEN_ID"), os.Getenv("PROXMOXVE_SECRET"), insecure)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := deleteFunc(client); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
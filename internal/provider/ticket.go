@@ -0,0 +1,310 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	proxmox "github.com/c10l/proxmoxve-client-go/api"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/pquerna/otp/totp"
+)
+
+// ticketRenewAfter is the age after which a ticket is renewed before being
+// used again. Proxmox VE accepts tickets for two hours after they are issued.
+const ticketRenewAfter = 1 * time.Hour
+
+// ticketCredentials are the credentials a ticket is acquired with.
+type ticketCredentials struct {
+	// Username includes the realm, e.g. `terraform@pve`.
+	Username string
+	Password string
+
+	// TOTPSeed, YubicoOTP and RecoveryKey are the second factors the login is
+	// completed with, if the user has any. The first one allowed by the user
+	// is used.
+	TOTPSeed    string
+	YubicoOTP   string
+	RecoveryKey string
+}
+
+// tfaChallenge lists the second factors a user can complete a login with. It
+// is sent by Proxmox VE in the ticket returned for the first step of the login.
+type tfaChallenge struct {
+	TOTP     bool            `json:"totp"`
+	Yubico   bool            `json:"yubico"`
+	Recovery json.RawMessage `json:"recovery"`
+	WebAuthn json.RawMessage `json:"webauthn"`
+	U2F      json.RawMessage `json:"u2f"`
+}
+
+// parseTFAChallenge decodes the challenge carried by a ticket such as
+// `PVE:!tfa!{"totp"%3Atrue}:64C0FFEE::signature`, whose colons are
+// percent-encoded.
+func parseTFAChallenge(tkt string) (*tfaChallenge, error) {
+	_, rest, ok := strings.Cut(tkt, ":!tfa!")
+	if !ok {
+		return nil, errors.New("no second factor challenge in the ticket")
+	}
+	data, _, _ := strings.Cut(rest, ":")
+	data, err := url.PathUnescape(data)
+	if err != nil {
+		return nil, err
+	}
+	var c tfaChallenge
+	if err := json.Unmarshal([]byte(data), &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// available returns the names of the second factors allowed by c.
+func (c *tfaChallenge) available() []string {
+	present := func(v json.RawMessage) bool {
+		s := string(v)
+		return s != "" && s != "null" && s != "false" && s != `"unavailable"`
+	}
+	var names []string
+	for _, f := range []struct {
+		name string
+		ok   bool
+	}{
+		{"totp", c.TOTP},
+		{"yubico", c.Yubico},
+		{"recovery", present(c.Recovery)},
+		{"webauthn", present(c.WebAuthn)},
+		{"u2f", present(c.U2F)},
+	} {
+		if f.ok {
+			names = append(names, f.name)
+		}
+	}
+	return names
+}
+
+// secondFactor returns the response to the challenge of the ticket tkt, e.g.
+// `totp:123456`, with the first second factor of c allowed by the challenge.
+// If the challenge cannot be decoded, the first one configured is tried.
+func (c ticketCredentials) secondFactor(tkt string) (string, error) {
+	var allowed []string
+	challenge, err := parseTFAChallenge(tkt)
+	if err == nil {
+		allowed = challenge.available()
+	}
+	isAllowed := func(name string) bool {
+		for _, a := range allowed {
+			if a == name {
+				return true
+			}
+		}
+		return challenge == nil
+	}
+
+	switch {
+	case c.TOTPSeed != "" && isAllowed("totp"):
+		code, err := totp.GenerateCode(c.TOTPSeed, time.Now())
+		if err != nil {
+			return "", fmt.Errorf("unable to generate TOTP code: %w", err)
+		}
+		return "totp:" + code, nil
+	case c.YubicoOTP != "" && isAllowed("yubico"):
+		return "yubico:" + c.YubicoOTP, nil
+	case c.RecoveryKey != "" && isAllowed("recovery"):
+		return "recovery:" + c.RecoveryKey, nil
+	case challenge == nil:
+		return "", fmt.Errorf("user %s has two-factor authentication enabled: one of totp_seed, yubico_otp or recovery_key is required", c.Username)
+	}
+	return "", fmt.Errorf("user %s has two-factor authentication enabled with %s: one of totp_seed, yubico_otp or recovery_key is required for a method it allows; WebAuthn and U2F are not supported",
+		c.Username, strings.Join(allowed, ", "))
+}
+
+// ticket is returned by /access/ticket.
+type ticket struct {
+	Username            string `json:"username"`
+	Ticket              string `json:"ticket"`
+	CSRFPreventionToken string `json:"CSRFPreventionToken"`
+	NeedTFA             int    `json:"NeedTFA"`
+
+	issued time.Time
+}
+
+// ticketSource acquires a ticket for a user, and renews it before it expires.
+type ticketSource struct {
+	credentials ticketCredentials
+	apiURL      url.URL
+	httpClient  *http.Client
+
+	mu     sync.Mutex
+	ticket *ticket
+
+	// recoveryKeyUsed is set once the recovery key of credentials completed a
+	// login. It is not accepted again, so later logins cannot use it.
+	recoveryKeyUsed bool
+}
+
+// get returns a valid ticket, logging in or renewing the current ticket first
+// if needed.
+func (s *ticketSource) get(ctx context.Context) (*ticket, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ticket != nil && time.Since(s.ticket.issued) < ticketRenewAfter {
+		return s.ticket, nil
+	}
+	if s.ticket != nil {
+		// A ticket is renewed by logging in with the ticket as password.
+		t, err := s.request(ctx, url.Values{"username": {s.credentials.Username}, "password": {s.ticket.Ticket}})
+		if err == nil {
+			tflog.Debug(ctx, "Renewed Proxmox VE ticket", map[string]interface{}{"username": s.credentials.Username})
+			s.ticket = t
+			return t, nil
+		}
+		tflog.Debug(ctx, "Unable to renew Proxmox VE ticket, logging in again", map[string]interface{}{"error": err.Error()})
+	}
+
+	t, err := s.login(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.ticket = t
+	return t, nil
+}
+
+// invalidate discards t if it is still the current ticket, so that the next
+// call to get logs in again.
+func (s *ticketSource) invalidate(t *ticket) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ticket == t {
+		s.ticket = nil
+	}
+}
+
+func (s *ticketSource) login(ctx context.Context) (*ticket, error) {
+	t, err := s.request(ctx, url.Values{
+		"username":   {s.credentials.Username},
+		"password":   {s.credentials.Password},
+		"new-format": {"1"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if t.NeedTFA == 0 {
+		return t, nil
+	}
+
+	// The ticket returned so far only allows completing the second factor.
+	response, err := s.credentials.secondFactor(t.Ticket)
+	if err != nil {
+		return nil, err
+	}
+	isRecovery := strings.HasPrefix(response, "recovery:")
+	if isRecovery && s.recoveryKeyUsed {
+		return nil, fmt.Errorf("user %s has to log in again, but the recovery key was used by the first login of the run and is accepted once: "+
+			"set totp_seed or yubico_otp for the provider to log in again during a run", s.credentials.Username)
+	}
+	t, err = s.request(ctx, url.Values{
+		"username":      {s.credentials.Username},
+		"password":      {response},
+		"tfa-challenge": {t.Ticket},
+		"new-format":    {"1"},
+	})
+	if err == nil && isRecovery {
+		s.recoveryKeyUsed = true
+	}
+	return t, err
+}
+
+func (s *ticketSource) request(ctx context.Context, form url.Values) (*ticket, error) {
+	ticketURL := s.apiURL
+	ticketURL.Path += "/access/ticket"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ticketURL.String(), strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s\n%s", resp.Status, body)
+	}
+
+	var data struct {
+		Data *ticket `json:"data"`
+	}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, err
+	}
+	if data.Data == nil || data.Data.Ticket == "" {
+		return nil, errors.New("no ticket returned by /access/ticket")
+	}
+	data.Data.issued = time.Now()
+	return data.Data, nil
+}
+
+// ticketTransport authenticates requests with a ticket from source, sending
+// the CSRF prevention token along with it. A request rejected because the
+// ticket expired is sent again once with a new ticket.
+type ticketTransport struct {
+	next   http.RoundTripper
+	source *ticketSource
+}
+
+func (t *ticketTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	for attempt := 0; ; attempt++ {
+		tkt, err := t.source.get(ctx)
+		if err != nil {
+			return nil, err
+		}
+		r := req.Clone(ctx)
+		r.Header.Set("Cookie", "PVEAuthCookie="+tkt.Ticket)
+		r.Header.Set("CSRFPreventionToken", tkt.CSRFPreventionToken)
+
+		resp, err := t.next.RoundTrip(r)
+		if err != nil || resp.StatusCode != http.StatusUnauthorized || attempt > 0 || !rewindBody(req) {
+			return resp, err
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		t.source.invalidate(tkt)
+	}
+}
+
+// withTicket makes c authenticate its requests with a ticket acquired with
+// credentials, which is acquired right away to report invalid credentials early.
+// A warning is added to diags if a recovery key is consumed to do so.
+func withTicket(ctx context.Context, c *proxmox.Client, credentials ticketCredentials, diags *diag.Diagnostics) (*proxmox.Client, error) {
+	source := &ticketSource{
+		credentials: credentials,
+		apiURL:      c.APIurl,
+		httpClient:  &http.Client{Transport: transport(c.HTTPClient)},
+	}
+	if _, err := source.get(ctx); err != nil {
+		return nil, err
+	}
+	if source.recoveryKeyUsed {
+		diags.AddWarning(
+			"Recovery key used",
+			fmt.Sprintf("The login of user %s was completed with a recovery key, which Proxmox VE does not accept again. Replace recovery_key before the next run, or set totp_seed or yubico_otp instead.", credentials.Username),
+		)
+	}
+	c.HTTPClient.Transport = &ticketTransport{next: transport(c.HTTPClient), source: source}
+	return c, nil
+}
@@ -0,0 +1,188 @@
+package provider
+
+import (
+	"context"
+	"crypto/tls"
+	"testing"
+	"time"
+
+	"terraform-provider-proxmoxve/internal/pvemock"
+
+	proxmox "github.com/c10l/proxmoxve-client-go/api"
+	"github.com/c10l/proxmoxve-client-go/api/cluster/firewall/aliases"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testTicketClient(t *testing.T, s *pvemock.Server, credentials ticketCredentials) (*proxmox.Client, error) {
+	client, err := newClient(s.URL, newHTTPTransport(&tls.Config{InsecureSkipVerify: true}, nil, nil))
+	require.NoError(t, err)
+	return withTicket(context.Background(), client, credentials, &diag.Diagnostics{})
+}
+
+func TestTicketTransport(t *testing.T) {
+	s := pvemock.New()
+	t.Cleanup(s.Close)
+	s.AddUser("terraform@pve", "hunter2")
+
+	_, err := testTicketClient(t, s, ticketCredentials{Username: "terraform@pve", Password: "wrong"})
+	assert.ErrorContains(t, err, "401 authentication failure")
+
+	client, err := testTicketClient(t, s, ticketCredentials{Username: "terraform@pve", Password: "hunter2"})
+	require.NoError(t, err)
+	source := client.HTTPClient.Transport.(*ticketTransport).source
+
+	// Writes need the CSRF prevention token.
+	require.NoError(t, aliases.PostRequest{Client: client, Name: "a", CIDR: "10.0.0.1"}.Post())
+
+	// An old ticket is renewed before being used.
+	first := source.ticket
+	first.issued = time.Now().Add(-ticketRenewAfter)
+	_, err = aliases.GetRequest{Client: client}.Get()
+	require.NoError(t, err)
+	assert.NotEqual(t, first.Ticket, source.ticket.Ticket)
+}
+
+func TestTicketTransportExpired(t *testing.T) {
+	s := pvemock.New()
+	t.Cleanup(s.Close)
+	s.TicketLifetime = 50 * time.Millisecond
+
+	client, err := testTicketClient(t, s, ticketCredentials{Username: "root@pam", Password: s.RootPassword})
+	require.NoError(t, err)
+	first := client.HTTPClient.Transport.(*ticketTransport).source.ticket
+
+	// The server rejects the ticket before it is due for renewal, so the
+	// client logs in again.
+	time.Sleep(100 * time.Millisecond)
+	require.NoError(t, aliases.PostRequest{Client: client, Name: "a", CIDR: "10.0.0.1"}.Post())
+	assert.NotEqual(t, first.Ticket, client.HTTPClient.Transport.(*ticketTransport).source.ticket.Ticket)
+}
+
+func TestTicketTransportTFA(t *testing.T) {
+	s := pvemock.New()
+	t.Cleanup(s.Close)
+	s.AddUser("terraform@pve", "hunter2")
+	seed := "JBSWY3DPEHPK3PXP"
+	s.SetTFA("terraform@pve", pvemock.TFA{TOTPSecret: seed, YubicoOTPs: []string{"ccccccbcgujh", "ccccccbcgujk"}, RecoveryKeys: []string{"4f3a-9b2c-0d1e-7a6b"}})
+	login := func(credentials ticketCredentials) error {
+		credentials.Username, credentials.Password = "terraform@pve", "hunter2"
+		client, err := testTicketClient(t, s, credentials)
+		if err != nil {
+			return err
+		}
+		_, err = aliases.GetRequest{Client: client}.Get()
+		return err
+	}
+
+	assert.ErrorContains(t, login(ticketCredentials{}), "enabled with totp, yubico, recovery: one of totp_seed, yubico_otp or recovery_key is required")
+	assert.NoError(t, login(ticketCredentials{TOTPSeed: seed}))
+	// The first second factor allowed is used.
+	assert.NoError(t, login(ticketCredentials{TOTPSeed: seed, YubicoOTP: "ccccccbcgujh"}))
+
+	// One-time passwords and recovery keys are accepted once.
+	assert.NoError(t, login(ticketCredentials{YubicoOTP: "ccccccbcgujh"}))
+	assert.ErrorContains(t, login(ticketCredentials{YubicoOTP: "ccccccbcgujh"}), "401 authentication failure")
+	assert.NoError(t, login(ticketCredentials{RecoveryKey: "4f3a-9b2c-0d1e-7a6b"}))
+
+	// WebAuthn cannot be used, so a recovery key is the only way in.
+	s.SetTFA("terraform@pve", pvemock.TFA{WebAuthn: true, RecoveryKeys: []string{"4f3a-9b2c-0d1e-7a6b"}})
+	assert.ErrorContains(t, login(ticketCredentials{TOTPSeed: seed}), "enabled with recovery, webauthn")
+	assert.NoError(t, login(ticketCredentials{TOTPSeed: seed, RecoveryKey: "4f3a-9b2c-0d1e-7a6b"}))
+}
+
+func TestTicketTransportRecoveryKey(t *testing.T) {
+	s := pvemock.New()
+	t.Cleanup(s.Close)
+	s.AddUser("terraform@pve", "hunter2")
+	s.SetTFA("terraform@pve", pvemock.TFA{WebAuthn: true, RecoveryKeys: []string{"4f3a-9b2c-0d1e-7a6b", "8c1d-2e5f-6a7b-3c9d"}})
+
+	client, err := newClient(s.URL, newHTTPTransport(&tls.Config{InsecureSkipVerify: true}, nil, nil))
+	require.NoError(t, err)
+	var diags diag.Diagnostics
+	client, err = withTicket(context.Background(), client, ticketCredentials{Username: "terraform@pve", Password: "hunter2", RecoveryKey: "4f3a-9b2c-0d1e-7a6b"}, &diags)
+	require.NoError(t, err)
+	require.Len(t, diags.Warnings(), 1)
+	assert.Equal(t, "Recovery key used", diags.Warnings()[0].Summary())
+
+	// Renewing the ticket needs no second factor.
+	source := client.HTTPClient.Transport.(*ticketTransport).source
+	source.ticket.issued = time.Now().Add(-ticketRenewAfter)
+	_, err = aliases.GetRequest{Client: client}.Get()
+	require.NoError(t, err)
+
+	// Logging in again would need the recovery key used already.
+	source.invalidate(source.ticket)
+	_, err = aliases.GetRequest{Client: client}.Get()
+	assert.ErrorContains(t, err, "user terraform@pve has to log in again, but the recovery key was used by the first login of the run")
+}
+
+func TestParseTFAChallenge(t *testing.T) {
+	c, err := parseTFAChallenge(`PVE:!tfa!{"totp"%3Atrue,"recovery"%3A"low","webauthn"%3A{"publicKey"%3A{}}}:64C0FFEE::c2lnbmF0dXJl`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"totp", "recovery", "webauthn"}, c.available())
+
+	c, err = parseTFAChallenge(`PVE:!tfa!{"yubico"%3Atrue,"recovery"%3A"unavailable"}:64C0FFEE::c2lnbmF0dXJl`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"yubico"}, c.available())
+
+	_, err = parseTFAChallenge("PVE:root@pam:64C0FFEE::c2lnbmF0dXJl")
+	assert.Error(t, err)
+
+	// Without a challenge, the first second factor configured is tried.
+	response, err := ticketCredentials{YubicoOTP: "ccccccbcgujh", RecoveryKey: "key"}.secondFactor("PVE:!tfa!garbled")
+	require.NoError(t, err)
+	assert.Equal(t, "yubico:ccccccbcgujh", response)
+}
+
+func TestGetTicketCredentials(t *testing.T) {
+	for _, env := range []string{"PROXMOXVE_USERNAME", "PROXMOXVE_PASSWORD", "PROXMOXVE_REALM", "PROXMOXVE_ROOT_PASSWORD", "PROXMOXVE_TOTPSEED", "PROXMOXVE_YUBICO_OTP", "PROXMOXVE_RECOVERY_KEY"} {
+		t.Setenv(env, "")
+	}
+	model := func(username, password, realm, rootPassword string) ProxmoxVEProviderModel {
+		value := func(v string) types.String {
+			if v == "" {
+				return types.StringNull()
+			}
+			return types.StringValue(v)
+		}
+		return ProxmoxVEProviderModel{
+			Username:     value(username),
+			Password:     value(password),
+			Realm:        value(realm),
+			RootPassword: value(rootPassword),
+			TOTPSeed:     types.StringNull(),
+			YubicoOTP:    types.StringNull(),
+			RecoveryKey:  types.StringNull(),
+		}
+	}
+
+	for _, tc := range []struct {
+		name     string
+		data     ProxmoxVEProviderModel
+		username string
+		err      string
+	}{
+		{"root password", model("", "", "", "secret"), "root@pam", ""},
+		{"default realm", model("terraform", "secret", "", ""), "terraform@pam", ""},
+		{"realm", model("terraform", "secret", "ldap", ""), "terraform@ldap", ""},
+		{"realm in username", model("terraform@pve", "secret", "", ""), "terraform@pve", ""},
+		{"same realm", model("terraform@pve", "secret", "pve", ""), "terraform@pve", ""},
+		{"conflicting realm", model("terraform@pve", "secret", "ldap", ""), "", `realm "ldap" conflicts`},
+		{"no password", model("terraform@pve", "", "", "secret"), "", "password cannot be empty"},
+		{"nothing", model("", "", "", ""), "", "cannot be empty"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			credentials, err := getTicketCredentials(tc.data)
+			if tc.err != "" {
+				assert.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.username, credentials.Username)
+			assert.Equal(t, "secret", credentials.Password)
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package provider
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// tlsOptions configures how the certificates of the API servers are verified.
+type tlsOptions struct {
+	// Insecure disables verification altogether.
+	Insecure bool
+
+	// CACertPEM holds CA certificates trusted in addition to the system's.
+	CACertPEM []byte
+
+	// FingerprintSHA256 pins the certificate of the API servers. It is
+	// hex-encoded, optionally colon-separated as shown by Proxmox VE.
+	FingerprintSHA256 string
+}
+
+// config returns the TLS configuration of the connections to the API servers.
+//
+// A pinned certificate is trusted even if it is self-signed or issued for
+// another name, like Proxmox VE's own tooling does, unless CA certificates are
+// set too, in which case it must be issued by one of them.
+func (o tlsOptions) config() (*tls.Config, error) {
+	if o.Insecure && (len(o.CACertPEM) > 0 || o.FingerprintSHA256 != "") {
+		return nil, errors.New("tls_insecure cannot be combined with a CA certificate or fingerprint")
+	}
+	cfg := &tls.Config{InsecureSkipVerify: o.Insecure}
+
+	if len(o.CACertPEM) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(o.CACertPEM) {
+			return nil, errors.New("the CA certificate does not contain any PEM-encoded certificate")
+		}
+		cfg.RootCAs = pool
+	}
+
+	if o.FingerprintSHA256 != "" {
+		pin, err := parseFingerprint(o.FingerprintSHA256)
+		if err != nil {
+			return nil, err
+		}
+		verifyChain := cfg.RootCAs != nil
+		roots := cfg.RootCAs
+		// The chain is verified below, if at all.
+		cfg.InsecureSkipVerify = true
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			if len(cs.PeerCertificates) == 0 {
+				return errors.New("the server did not present a certificate")
+			}
+			leaf := cs.PeerCertificates[0]
+			if verifyChain {
+				intermediates := x509.NewCertPool()
+				for _, cert := range cs.PeerCertificates[1:] {
+					intermediates.AddCert(cert)
+				}
+				if _, err := leaf.Verify(x509.VerifyOptions{Roots: roots, Intermediates: intermediates, DNSName: cs.ServerName}); err != nil {
+					return err
+				}
+			}
+			if sum := sha256.Sum256(leaf.Raw); !bytes.Equal(sum[:], pin) {
+				return &fingerprintMismatchError{Fingerprint: formatFingerprint(sum[:])}
+			}
+			return nil
+		}
+	}
+
+	return cfg, nil
+}
+
+// fingerprintMismatchError is returned when a server presents another
+// certificate than the pinned one.
+type fingerprintMismatchError struct {
+	Fingerprint string
+}
+
+func (e *fingerprintMismatchError) Error() string {
+	return fmt.Sprintf("the certificate fingerprint %s does not match tls_fingerprint_sha256", e.Fingerprint)
+}
+
+// isTLSError reports whether err is a failure to verify the certificate of a
+// server, or to speak TLS with it at all.
+func isTLSError(err error) bool {
+	var (
+		verificationErr  *tls.CertificateVerificationError
+		recordHeaderErr  tls.RecordHeaderError
+		fingerprintErr   *fingerprintMismatchError
+		unknownAuthority x509.UnknownAuthorityError
+		hostnameErr      x509.HostnameError
+		invalidErr       x509.CertificateInvalidError
+	)
+	return errors.As(err, &verificationErr) ||
+		errors.As(err, &recordHeaderErr) ||
+		errors.As(err, &fingerprintErr) ||
+		errors.As(err, &unknownAuthority) ||
+		errors.As(err, &hostnameErr) ||
+		errors.As(err, &invalidErr)
+}
+
+// parseFingerprint decodes a SHA-256 fingerprint such as `AB:CD:...` or
+// `abcd...`.
+func parseFingerprint(fingerprint string) ([]byte, error) {
+	pin, err := hex.DecodeString(strings.ReplaceAll(strings.TrimSpace(fingerprint), ":", ""))
+	if err != nil || len(pin) != sha256.Size {
+		return nil, fmt.Errorf("%q is not a SHA-256 fingerprint, e.g. AB:CD:...:EF", fingerprint)
+	}
+	return pin, nil
+}
+
+// formatFingerprint encodes a fingerprint the way the Proxmox VE web UI shows
+// it.
+func formatFingerprint(sum []byte) string {
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(parts, ":")
+}
@@ -0,0 +1,128 @@
+package provider
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testTLSServer returns a server with a self-signed certificate, its
+// certificate PEM-encoded, and its fingerprint.
+func testTLSServer(t *testing.T) (*httptest.Server, []byte, string) {
+	s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(s.Close)
+	cert := s.Certificate()
+	sum := sha256.Sum256(cert.Raw)
+	return s, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}), formatFingerprint(sum[:])
+}
+
+// testCertificate returns a self-signed certificate PEM-encoded, and its
+// fingerprint.
+func testCertificate(t *testing.T) ([]byte, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "pve.example.com"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	sum := sha256.Sum256(der)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), formatFingerprint(sum[:])
+}
+
+func TestTLSOptions(t *testing.T) {
+	s, caPEM, fingerprint := testTLSServer(t)
+	otherPEM, otherFingerprint := testCertificate(t)
+
+	for _, tc := range []struct {
+		name    string
+		options tlsOptions
+		err     string
+	}{
+		{"system CAs", tlsOptions{}, "certificate signed by unknown authority"},
+		{"insecure", tlsOptions{Insecure: true}, ""},
+		{"CA", tlsOptions{CACertPEM: caPEM}, ""},
+		{"other CA", tlsOptions{CACertPEM: otherPEM}, "certificate signed by unknown authority"},
+		{"fingerprint", tlsOptions{FingerprintSHA256: fingerprint}, ""},
+		{"lowercase fingerprint", tlsOptions{FingerprintSHA256: strings.ToLower(strings.ReplaceAll(fingerprint, ":", ""))}, ""},
+		{"other fingerprint", tlsOptions{FingerprintSHA256: otherFingerprint}, "does not match tls_fingerprint_sha256"},
+		{"CA and fingerprint", tlsOptions{CACertPEM: caPEM, FingerprintSHA256: fingerprint}, ""},
+		{"other CA and fingerprint", tlsOptions{CACertPEM: otherPEM, FingerprintSHA256: fingerprint}, "certificate signed by unknown authority"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := tc.options.config()
+			require.NoError(t, err)
+			httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: cfg}}
+			resp, err := httpClient.Get(s.URL)
+			if tc.err != "" {
+				assert.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			resp.Body.Close()
+		})
+	}
+}
+
+func TestTLSOptionsInvalid(t *testing.T) {
+	caPEM, fingerprint := testCertificate(t)
+
+	_, err := tlsOptions{CACertPEM: []byte("not a certificate")}.config()
+	assert.ErrorContains(t, err, "does not contain any PEM-encoded certificate")
+
+	_, err = tlsOptions{FingerprintSHA256: fingerprint[3:]}.config()
+	assert.ErrorContains(t, err, "is not a SHA-256 fingerprint")
+
+	_, err = tlsOptions{Insecure: true, CACertPEM: caPEM}.config()
+	assert.ErrorContains(t, err, "tls_insecure cannot be combined")
+}
+
+func TestGetTLSConfig(t *testing.T) {
+	t.Setenv("PROXMOXVE_TLS_FINGERPRINT_SHA256", "")
+	caPEM, _ := testCertificate(t)
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, caPEM, 0o600))
+
+	data := ProxmoxVEProviderModel{CACertPEM: types.StringNull(), CACertFile: types.StringNull(), TLSFingerprintSHA256: types.StringNull()}
+	for _, env := range []string{"", string(caPEM), caFile} {
+		t.Setenv("PROXMOXVE_CA_CERT", env)
+		var diags diag.Diagnostics
+		cfg := getTLSConfig(data, false, &diags)
+		require.False(t, diags.HasError(), diags)
+		assert.Equal(t, env != "", cfg.RootCAs != nil, "PROXMOXVE_CA_CERT=%q", env)
+	}
+
+	t.Setenv("PROXMOXVE_CA_CERT", "")
+	var diags diag.Diagnostics
+	data.CACertFile = types.StringValue(filepath.Join(t.TempDir(), "missing.pem"))
+	getTLSConfig(data, false, &diags)
+	assert.True(t, diags.HasError())
+
+	diags = nil
+	data.CACertPEM = types.StringValue(string(caPEM))
+	getTLSConfig(data, false, &diags)
+	assert.True(t, diags.HasError())
+}
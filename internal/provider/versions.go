@@ -0,0 +1,107 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// pveRelease is a point release of Proxmox VE, e.g. 7.3.
+type pveRelease struct {
+	Major int
+	Minor int
+}
+
+// parsePVERelease parses a release in `x.y` format, as returned by /version.
+// Anything following the minor version, e.g. the `-4` of `7.3-4`, is ignored.
+func parsePVERelease(s string) (pveRelease, error) {
+	major, rest, ok := strings.Cut(s, ".")
+	if !ok {
+		return pveRelease{}, fmt.Errorf("%q is not a Proxmox VE release in x.y format", s)
+	}
+	if i := strings.IndexFunc(rest, func(r rune) bool { return r < '0' || r > '9' }); i >= 0 {
+		rest = rest[:i]
+	}
+	var r pveRelease
+	var err1, err2 error
+	r.Major, err1 = strconv.Atoi(major)
+	r.Minor, err2 = strconv.Atoi(rest)
+	if err1 != nil || err2 != nil {
+		return pveRelease{}, fmt.Errorf("%q is not a Proxmox VE release in x.y format", s)
+	}
+	return r, nil
+}
+
+// before reports whether r is older than other.
+func (r pveRelease) before(other pveRelease) bool {
+	return r.Major < other.Major || (r.Major == other.Major && r.Minor < other.Minor)
+}
+
+func (r pveRelease) String() string {
+	return fmt.Sprintf("%d.%d", r.Major, r.Minor)
+}
+
+// versionRequirements are the oldest releases of Proxmox VE supporting a
+// resource and some of its attributes.
+type versionRequirements struct {
+	// Resource is the oldest release supporting the resource, or the zero
+	// value if any release does.
+	Resource pveRelease
+	// Attributes are the oldest releases supporting the attributes newer than
+	// the resource, by name.
+	Attributes map[string]pveRelease
+}
+
+// checkVersion reports at plan time that the resource typeName, or an
+// attribute set in its configuration, is not supported by the release of
+// Proxmox VE the provider connected to, instead of the API rejecting the
+// parameters when applying the change. It is called by the ModifyPlan method
+// of the resources.
+//
+// Nothing is checked if the release is not known, e.g. because the provider
+// has no credentials.
+func (d *providerData) checkVersion(ctx context.Context, typeName string, requirements versionRequirements, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if d == nil || d.release == "" || req.Plan.Raw.IsNull() || req.Plan.Raw.Equal(req.State.Raw) {
+		return
+	}
+	release, err := parsePVERelease(d.release)
+	if err != nil {
+		tflog.Warn(ctx, "Unable to check the Proxmox VE release", map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	if release.before(requirements.Resource) {
+		resp.Diagnostics.AddError(
+			"Unsupported resource",
+			fmt.Sprintf("%s requires PVE >= %s, but the cluster runs %s.", typeName, requirements.Resource, release),
+		)
+		return
+	}
+
+	var config map[string]tftypes.Value
+	if err := req.Config.Raw.As(&config); err != nil {
+		return
+	}
+	names := make([]string, 0, len(requirements.Attributes))
+	for name := range requirements.Attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		minimum := requirements.Attributes[name]
+		if value, ok := config[name]; ok && !value.IsNull() && release.before(minimum) {
+			resp.Diagnostics.AddAttributeError(
+				path.Root(name),
+				"Unsupported attribute",
+				fmt.Sprintf("attribute %s requires PVE >= %s, but the cluster runs %s.", name, minimum, release),
+			)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePVERelease(t *testing.T) {
+	for s, expected := range map[string]pveRelease{
+		"7.3":   {Major: 7, Minor: 3},
+		"6.4-1": {Major: 6, Minor: 4},
+		"8.10":  {Major: 8, Minor: 10},
+	} {
+		r, err := parsePVERelease(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, expected, r, s)
+	}
+	for _, s := range []string{"", "7", "seven.3", "7.x"} {
+		_, err := parsePVERelease(s)
+		assert.Error(t, err, s)
+	}
+
+	assert.True(t, pveRelease{Major: 6, Minor: 4}.before(pveRelease{Major: 7, Minor: 0}))
+	assert.True(t, pveRelease{Major: 7, Minor: 2}.before(pveRelease{Major: 7, Minor: 10}))
+	assert.False(t, pveRelease{Major: 7, Minor: 3}.before(pveRelease{Major: 7, Minor: 3}))
+	assert.False(t, pveRelease{Major: 7, Minor: 0}.before(pveRelease{}))
+}
+
+func TestCheckVersion(t *testing.T) {
+	requirements := versionRequirements{
+		Resource:   pveRelease{Major: 7, Minor: 0},
+		Attributes: map[string]pveRelease{"path": {Major: 7, Minor: 3}},
+	}
+	check := func(release string, req resource.ModifyPlanRequest) *resource.ModifyPlanResponse {
+		resp := &resource.ModifyPlanResponse{}
+		d := &providerData{release: release}
+		d.checkVersion(context.Background(), "storage_btrfs", requirements, req, resp)
+		return resp
+	}
+	create := testModifyPlanRequest(nil, map[string]string{"name": "btrfs", "path": "/srv"})
+
+	assert.False(t, check("7.3", create).Diagnostics.HasError())
+	// Nothing is checked if the release is not known.
+	assert.False(t, check("", create).Diagnostics.HasError())
+
+	resp := check("6.4", create)
+	require.True(t, resp.Diagnostics.HasError())
+	assert.Equal(t, "Unsupported resource", resp.Diagnostics[0].Summary())
+	assert.Equal(t, "storage_btrfs requires PVE >= 7.0, but the cluster runs 6.4.", resp.Diagnostics[0].Detail())
+
+	resp = check("7.2", create)
+	require.Len(t, resp.Diagnostics, 1)
+	assert.Equal(t, "Unsupported attribute", resp.Diagnostics[0].Summary())
+	assert.Equal(t, "attribute path requires PVE >= 7.3, but the cluster runs 7.2.", resp.Diagnostics[0].Detail())
+	assert.Equal(t, path.Root("path"), resp.Diagnostics[0].(interface{ Path() path.Path }).Path())
+
+	// Unchanged or deleted resources need no support.
+	state := map[string]string{"name": "btrfs", "path": "/srv"}
+	assert.False(t, check("6.4", testModifyPlanRequest(state, state)).Diagnostics.HasError())
+	assert.False(t, check("6.4", testModifyPlanRequest(state, nil)).Diagnostics.HasError())
+}
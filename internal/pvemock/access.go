@@ -0,0 +1,207 @@
+package pvemock
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pquerna/otp/totp"
+)
+
+type ticket struct {
+	UserID string
+	CSRF   string
+	Issued time.Time
+}
+
+// AddUser allows userid (e.g. `terraform@pve`) to acquire a ticket with password.
+func (s *Server) AddUser(userid, password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[userid] = password
+}
+
+func (s *Server) authenticated(r *http.Request) bool {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		id, secret, _ := strings.Cut(strings.TrimPrefix(auth, "PVEAPIToken="), "=")
+		return id == s.TokenID && secret == s.Secret
+	}
+	cookie, err := r.Cookie("PVEAuthCookie")
+	if err != nil {
+		return false
+	}
+	t := s.validTicket(cookie.Value)
+	if t == nil {
+		return false
+	}
+	return r.Method == http.MethodGet || r.Header.Get("CSRFPreventionToken") == t.CSRF
+}
+
+// validTicket returns the ticket called value unless it expired.
+func (s *Server) validTicket(value string) *ticket {
+	t, ok := s.tickets[value]
+	if !ok || time.Since(t.Issued) > s.TicketLifetime {
+		return nil
+	}
+	return t
+}
+
+// TFA are the second factors of a user, who then has to complete every login
+// with one of them.
+type TFA struct {
+	// TOTPSecret is the base32 secret the TOTP codes are generated from.
+	TOTPSecret string
+	// YubicoOTPs are the one-time passwords the Yubico servers would accept.
+	// Each is accepted once.
+	YubicoOTPs []string
+	// RecoveryKeys are the recovery keys not used yet. Each is accepted once.
+	RecoveryKeys []string
+	// WebAuthn is set if the user registered a WebAuthn device, which is
+	// offered in the challenge but never accepted.
+	WebAuthn bool
+}
+
+// challenge returns the JSON challenge listing the second factors available.
+func (tfa *TFA) challenge() string {
+	challenge := map[string]any{}
+	if tfa.TOTPSecret != "" {
+		challenge["totp"] = true
+	}
+	if len(tfa.YubicoOTPs) > 0 {
+		challenge["yubico"] = true
+	}
+	if len(tfa.RecoveryKeys) > 0 {
+		challenge["recovery"] = "available"
+	}
+	if tfa.WebAuthn {
+		challenge["webauthn"] = map[string]any{"publicKey": map[string]any{"challenge": randomHex(16)}}
+	}
+	data, _ := json.Marshal(challenge)
+	return string(data)
+}
+
+// verify reports whether response, e.g. `totp:123456`, completes the
+// challenge, consuming the one-time passwords and recovery keys.
+func (tfa *TFA) verify(response string) bool {
+	kind, value, _ := strings.Cut(response, ":")
+	consume := func(values *[]string) bool {
+		for i, v := range *values {
+			if v == value {
+				*values = append((*values)[:i], (*values)[i+1:]...)
+				return true
+			}
+		}
+		return false
+	}
+	switch kind {
+	case "totp":
+		return tfa.TOTPSecret != "" && totp.Validate(value, tfa.TOTPSecret)
+	case "yubico":
+		return consume(&tfa.YubicoOTPs)
+	case "recovery":
+		return consume(&tfa.RecoveryKeys)
+	}
+	return false
+}
+
+// SetTFA requires userid to complete their logins with one of the second
+// factors of tfa.
+func (s *Server) SetTFA(userid string, tfa TFA) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tfa[userid] = &tfa
+}
+
+// createTicket logs a user in with either their password or, to renew it, a
+// ticket they were issued before. Users with second factors are given a
+// challenge instead, which they complete by sending it back as tfa-challenge
+// along with their response as password.
+func (s *Server) createTicket(w http.ResponseWriter, r *http.Request) {
+	username, password := r.Form.Get("username"), r.Form.Get("password")
+	if realm := r.Form.Get("realm"); realm != "" && !strings.Contains(username, "@") {
+		username += "@" + realm
+	}
+
+	if challenge := r.Form.Get("tfa-challenge"); challenge != "" {
+		userid, ok := s.challenges[challenge]
+		delete(s.challenges, challenge)
+		if !ok || userid != username || !s.tfa[username].verify(password) {
+			writeError(w, http.StatusUnauthorized, "authentication failure", nil)
+			return
+		}
+		s.issueTicket(w, username)
+		return
+	}
+
+	authenticated, renewal := false, false
+	if t := s.validTicket(password); t != nil {
+		authenticated, renewal = t.UserID == username, true
+	} else if username == "root@pam" {
+		authenticated = password == s.RootPassword
+	} else if pwd, ok := s.users[username]; ok {
+		authenticated = password == pwd
+	}
+	if !authenticated {
+		writeError(w, http.StatusUnauthorized, "authentication failure", nil)
+		return
+	}
+
+	if tfa, ok := s.tfa[username]; ok && !renewal {
+		// Like pveproxy, the challenge is carried by a ticket of its own,
+		// whose colons are percent-encoded.
+		value := fmt.Sprintf("PVE:!tfa!%s:%s", strings.ReplaceAll(tfa.challenge(), ":", "%3A"), strings.ToUpper(randomHex(4)))
+		s.challenges[value] = username
+		writeData(w, map[string]any{
+			"username": username,
+			"ticket":   value,
+			"NeedTFA":  1,
+		})
+		return
+	}
+	s.issueTicket(w, username)
+}
+
+// issueTicket replies with a new ticket for username.
+func (s *Server) issueTicket(w http.ResponseWriter, username string) {
+	value := fmt.Sprintf("PVE:%s:%s", username, strings.ToUpper(randomHex(4)))
+	t := &ticket{UserID: username, CSRF: randomHex(16), Issued: time.Now()}
+	s.tickets[value] = t
+	writeData(w, map[string]any{
+		"username":            username,
+		"ticket":              value,
+		"CSRFPreventionToken": t.CSRF,
+	})
+}
+
+// privileges are the privileges of the built-in Administrator role.
+var privileges = []string{
+	"Datastore.Allocate", "Datastore.AllocateSpace", "Datastore.AllocateTemplate", "Datastore.Audit",
+	"Permissions.Modify", "Pool.Allocate", "Pool.Audit", "Realm.Allocate", "Realm.AllocateUser",
+	"SDN.Allocate", "SDN.Audit", "Sys.Audit", "Sys.Console", "Sys.Incoming", "Sys.Modify", "Sys.PowerMgmt", "Sys.Syslog",
+	"User.Modify", "VM.Allocate", "VM.Audit", "VM.Backup", "VM.Clone", "VM.Config.CDROM", "VM.Config.CPU",
+	"VM.Config.Cloudinit", "VM.Config.Disk", "VM.Config.HWType", "VM.Config.Memory", "VM.Config.Network",
+	"VM.Config.Options", "VM.Console", "VM.Migrate", "VM.Monitor", "VM.PowerMgmt", "VM.Snapshot", "VM.Snapshot.Rollback",
+}
+
+// SetPermissions sets the privileges returned by /access/permissions for any
+// credential, by ACL path, each telling whether it propagates. They are not
+// enforced. By default, every privilege is granted on `/`.
+func (s *Server) SetPermissions(perms map[string]map[string]int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.perms = perms
+}
+
+func (s *Server) servePermissions(w http.ResponseWriter) {
+	if s.perms != nil {
+		writeData(w, s.perms)
+		return
+	}
+	all := map[string]int{}
+	for _, p := range privileges {
+		all[p] = 1
+	}
+	writeData(w, map[string]any{"/": all})
+}
@@ -0,0 +1,179 @@
+package pvemock
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const defaultACMEDirectory = "https://acme-v02.api.letsencrypt.org/directory"
+
+type acmeAccount struct {
+	Name      string
+	Contact   string
+	Directory string
+	TOS       string
+}
+
+func (s *Server) serveACME(w http.ResponseWriter, r *http.Request, path []string) {
+	if len(path) == 0 {
+		writeError(w, http.StatusNotImplemented, "Method not implemented", nil)
+		return
+	}
+	switch path[0] {
+	case "account":
+		s.serveACMEAccounts(w, r, path[1:])
+	case "plugins":
+		s.serveACMEPlugins(w, r, path[1:])
+	default:
+		writeError(w, http.StatusNotImplemented, "Method not implemented", nil)
+	}
+}
+
+func (s *Server) serveACMEAccounts(w http.ResponseWriter, r *http.Request, path []string) {
+	switch {
+	case len(path) == 0 && r.Method == http.MethodGet:
+		list := []map[string]any{}
+		for _, name := range sortedKeys(s.accounts) {
+			list = append(list, map[string]any{"name": name})
+		}
+		writeData(w, list)
+	case len(path) == 0 && r.Method == http.MethodPost:
+		name := r.Form.Get("name")
+		if name == "" {
+			name = "default"
+		}
+		if errs := requireParams(r, "contact"); errs != nil {
+			writeParamError(w, errs)
+			return
+		}
+		if _, exists := s.accounts[name]; exists {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("ACME account config file '%s' already exists.", name), nil)
+			return
+		}
+		directory := r.Form.Get("directory")
+		if directory == "" {
+			directory = defaultACMEDirectory
+		}
+		s.accounts[name] = &acmeAccount{Name: name, Contact: r.Form.Get("contact"), Directory: directory, TOS: r.Form.Get("tos_url")}
+		writeData(w, s.startTask("acmeregister", name, "Registering new ACME account..", "TASK OK"))
+	case len(path) == 1:
+		acc, ok := s.accounts[path[0]]
+		if !ok {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("ACME account config file '%s' does not exist", path[0]), nil)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			contacts := []string{}
+			for _, c := range strings.Split(acc.Contact, ",") {
+				contacts = append(contacts, "mailto:"+c)
+			}
+			writeData(w, map[string]any{
+				"account": map[string]any{
+					"contact": contacts,
+					"status":  "valid",
+				},
+				"directory": acc.Directory,
+				"location":  strings.TrimSuffix(acc.Directory, "/directory") + "/acct/" + acc.Name,
+				"tos":       acc.TOS,
+			})
+		case http.MethodPut:
+			if contact := r.Form.Get("contact"); contact != "" {
+				acc.Contact = contact
+			}
+			writeData(w, s.startTask("acmeupdate", acc.Name, "TASK OK"))
+		case http.MethodDelete:
+			delete(s.accounts, acc.Name)
+			writeData(w, s.startTask("acmedeactivate", acc.Name, "TASK OK"))
+		}
+	default:
+		writeError(w, http.StatusNotImplemented, "Method not implemented", nil)
+	}
+}
+
+func (s *Server) serveACMEPlugins(w http.ResponseWriter, r *http.Request, path []string) {
+	switch {
+	case len(path) == 0 && r.Method == http.MethodGet:
+		list := []map[string]any{}
+		for _, id := range sortedKeys(s.plugins) {
+			list = append(list, s.acmePluginItem(id, s.plugins[id]))
+		}
+		writeData(w, list)
+	case len(path) == 0 && r.Method == http.MethodPost:
+		id := r.Form.Get("id")
+		if errs := requireParams(r, "id", "type"); errs != nil {
+			writeParamError(w, errs)
+			return
+		}
+		if _, exists := s.plugins[id]; exists {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("ACME plugin ID '%s' already defined", id), nil)
+			return
+		}
+		plugin := map[string]string{"type": r.Form.Get("type")}
+		if errs := applyACMEPluginOptions(r, plugin); errs != nil {
+			writeParamError(w, errs)
+			return
+		}
+		s.plugins[id] = plugin
+		writeData(w, nil)
+	case len(path) == 1:
+		plugin, ok := s.plugins[path[0]]
+		if !ok {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("ACME plugin '%s' does not exist", path[0]), nil)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			writeData(w, s.acmePluginItem(path[0], plugin))
+		case http.MethodPut:
+			if errs := applyACMEPluginOptions(r, plugin); errs != nil {
+				writeParamError(w, errs)
+				return
+			}
+			for _, k := range deleteList(r) {
+				delete(plugin, k)
+			}
+			writeData(w, nil)
+		case http.MethodDelete:
+			delete(s.plugins, path[0])
+			writeData(w, nil)
+		}
+	default:
+		writeError(w, http.StatusNotImplemented, "Method not implemented", nil)
+	}
+}
+
+// applyACMEPluginOptions stores the plugin options of the request. The `data`
+// option is sent base64 encoded and stored decoded, as Proxmox VE does.
+func applyACMEPluginOptions(r *http.Request, plugin map[string]string) map[string]string {
+	for _, k := range []string{"api", "nodes", "disable", "validation-delay"} {
+		if r.Form.Has(k) {
+			plugin[k] = r.Form.Get(k)
+		}
+	}
+	if r.Form.Has("data") {
+		data, err := base64.StdEncoding.DecodeString(r.Form.Get("data"))
+		if err != nil {
+			return map[string]string{"data": "value is not base64 encoded"}
+		}
+		plugin["data"] = string(data)
+	}
+	return nil
+}
+
+func (s *Server) acmePluginItem(id string, plugin map[string]string) map[string]any {
+	item := map[string]any{"plugin": id, "digest": digest(s.plugins)}
+	for k, v := range plugin {
+		switch k {
+		case "disable", "validation-delay":
+			n, _ := strconv.Atoi(v)
+			item[k] = n
+		default:
+			item[k] = v
+		}
+	}
+	return item
+}
@@ -0,0 +1,465 @@
+package pvemock
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+type firewallAlias struct {
+	Name    string `json:"name"`
+	CIDR    string `json:"cidr"`
+	Comment string `json:"comment,omitempty"`
+}
+
+type ipsetEntry struct {
+	CIDR    string `json:"cidr"`
+	NoMatch bool   `json:"nomatch,omitempty"`
+	Comment string `json:"comment,omitempty"`
+}
+
+type firewallIPSet struct {
+	Name    string        `json:"name"`
+	Comment string        `json:"comment,omitempty"`
+	Entries []*ipsetEntry `json:"entries"`
+}
+
+type firewallGroup struct {
+	Name    string           `json:"group"`
+	Comment string           `json:"comment,omitempty"`
+	Rules   []map[string]any `json:"rules"`
+}
+
+// Like Proxmox VE, every firewall list has its own digest, returned along
+// each of its entries and checked by the changes made to it.
+
+func (s *Server) aliasesDigest() string {
+	return digest(s.aliases)
+}
+
+// ipsetsDigest covers the IPSets themselves, not their entries.
+func (s *Server) ipsetsDigest() string {
+	list := []map[string]string{}
+	for _, name := range sortedKeys(s.ipsets) {
+		list = append(list, map[string]string{"name": name, "comment": s.ipsets[name].Comment})
+	}
+	return digest(list)
+}
+
+func ipsetDigest(set *firewallIPSet) string {
+	return digest(set.Entries)
+}
+
+// groupsDigest covers the security groups themselves, not their rules.
+func (s *Server) groupsDigest() string {
+	list := []map[string]string{}
+	for _, name := range sortedKeys(s.groups) {
+		list = append(list, map[string]string{"group": name, "comment": s.groups[name].Comment})
+	}
+	return digest(list)
+}
+
+// checkDigest refuses a change made against an outdated list, replying with
+// the error of Proxmox VE, which ignores an empty digest.
+func checkDigest(w http.ResponseWriter, r *http.Request, current string) bool {
+	if d := r.Form.Get("digest"); d != "" && d != current {
+		writeError(w, http.StatusInternalServerError, "detected modified configuration - file changed by other user? Try again.", nil)
+		return false
+	}
+	return true
+}
+
+// rejectDigest refuses the digest sent to a method which takes none.
+func rejectDigest(w http.ResponseWriter, r *http.Request) bool {
+	if r.Form.Has("digest") {
+		writeParamError(w, map[string]string{"digest": "property is not defined in schema and the schema does not allow additional properties"})
+		return false
+	}
+	return true
+}
+
+func (s *Server) serveFirewall(w http.ResponseWriter, r *http.Request, path []string) {
+	if len(path) == 0 {
+		writeError(w, http.StatusNotImplemented, "Method not implemented", nil)
+		return
+	}
+	switch path[0] {
+	case "options":
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusNotImplemented, "Method not implemented", nil)
+			return
+		}
+		writeData(w, map[string]any{"digest": digest(map[string]any{})})
+	case "aliases":
+		s.serveAliases(w, r, path[1:])
+	case "ipset":
+		s.serveIPSets(w, r, path[1:])
+	case "groups":
+		s.serveGroups(w, r, path[1:])
+	case "refs":
+		s.serveRefs(w, r)
+	default:
+		writeError(w, http.StatusNotImplemented, "Method not implemented", nil)
+	}
+}
+
+func (s *Server) serveAliases(w http.ResponseWriter, r *http.Request, path []string) {
+	switch {
+	case len(path) == 0 && r.Method == http.MethodGet:
+		list := []map[string]any{}
+		for _, name := range sortedKeys(s.aliases) {
+			list = append(list, s.aliasItem(s.aliases[name]))
+		}
+		writeData(w, list)
+	case len(path) == 0 && r.Method == http.MethodPost:
+		if !rejectDigest(w, r) {
+			return
+		}
+		name, cidr := r.Form.Get("name"), r.Form.Get("cidr")
+		if errs := requireParams(r, "name", "cidr"); errs != nil {
+			writeParamError(w, errs)
+			return
+		}
+		if !validCIDR(cidr) {
+			writeParamError(w, map[string]string{"cidr": "value does not look like a valid CIDR network"})
+			return
+		}
+		if _, exists := s.aliases[name]; exists {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("alias '%s' already exists", name), nil)
+			return
+		}
+		s.aliases[name] = &firewallAlias{Name: name, CIDR: cidr, Comment: r.Form.Get("comment")}
+		writeData(w, nil)
+	case len(path) == 1:
+		a, ok := s.aliases[path[0]]
+		if !ok {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("no such alias '%s'", path[0]), nil)
+			return
+		}
+		if r.Method != http.MethodGet && !checkDigest(w, r, s.aliasesDigest()) {
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			writeData(w, s.aliasItem(a))
+		case http.MethodPut:
+			if cidr := r.Form.Get("cidr"); cidr != "" {
+				if !validCIDR(cidr) {
+					writeParamError(w, map[string]string{"cidr": "value does not look like a valid CIDR network"})
+					return
+				}
+				a.CIDR = cidr
+			}
+			if r.Form.Has("comment") {
+				a.Comment = r.Form.Get("comment")
+			}
+			if rename := r.Form.Get("rename"); rename != "" && rename != a.Name {
+				if _, exists := s.aliases[rename]; exists {
+					writeError(w, http.StatusInternalServerError, fmt.Sprintf("alias '%s' already exists", rename), nil)
+					return
+				}
+				delete(s.aliases, a.Name)
+				a.Name = rename
+				s.aliases[rename] = a
+			}
+			writeData(w, nil)
+		case http.MethodDelete:
+			delete(s.aliases, a.Name)
+			writeData(w, nil)
+		}
+	default:
+		writeError(w, http.StatusNotImplemented, "Method not implemented", nil)
+	}
+}
+
+func (s *Server) aliasItem(a *firewallAlias) map[string]any {
+	item := map[string]any{
+		"name":      a.Name,
+		"cidr":      a.CIDR,
+		"ipversion": ipVersion(a.CIDR),
+		"digest":    s.aliasesDigest(),
+	}
+	if a.Comment != "" {
+		item["comment"] = a.Comment
+	}
+	return item
+}
+
+func (s *Server) serveIPSets(w http.ResponseWriter, r *http.Request, path []string) {
+	switch {
+	case len(path) == 0 && r.Method == http.MethodGet:
+		list := []map[string]any{}
+		for _, name := range sortedKeys(s.ipsets) {
+			set := s.ipsets[name]
+			item := map[string]any{"name": set.Name, "digest": s.ipsetsDigest()}
+			if set.Comment != "" {
+				item["comment"] = set.Comment
+			}
+			list = append(list, item)
+		}
+		writeData(w, list)
+	case len(path) == 0 && r.Method == http.MethodPost:
+		s.createIPSet(w, r)
+	case len(path) >= 1:
+		set, ok := s.ipsets[path[0]]
+		if !ok {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("no such IPSet '%s'", path[0]), nil)
+			return
+		}
+		if len(path) == 1 {
+			s.serveIPSet(w, r, set)
+			return
+		}
+		s.serveIPSetEntry(w, r, set, strings.Join(path[1:], "/"))
+	}
+}
+
+func (s *Server) createIPSet(w http.ResponseWriter, r *http.Request) {
+	name, rename := r.Form.Get("name"), r.Form.Get("rename")
+	if errs := requireParams(r, "name"); errs != nil {
+		writeParamError(w, errs)
+		return
+	}
+	if !checkDigest(w, r, s.ipsetsDigest()) {
+		return
+	}
+	if rename != "" {
+		set, ok := s.ipsets[rename]
+		if !ok {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("IPSet '%s' does not exist", rename), nil)
+			return
+		}
+		if _, exists := s.ipsets[name]; exists && name != rename {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("IPSet '%s' already exists", name), nil)
+			return
+		}
+		delete(s.ipsets, rename)
+		set.Name = name
+		set.Comment = r.Form.Get("comment")
+		s.ipsets[name] = set
+		writeData(w, nil)
+		return
+	}
+	if _, exists := s.ipsets[name]; exists {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("IPSet '%s' already exists", name), nil)
+		return
+	}
+	s.ipsets[name] = &firewallIPSet{Name: name, Comment: r.Form.Get("comment"), Entries: []*ipsetEntry{}}
+	writeData(w, nil)
+}
+
+func (s *Server) serveIPSet(w http.ResponseWriter, r *http.Request, set *firewallIPSet) {
+	switch r.Method {
+	case http.MethodGet:
+		list := []map[string]any{}
+		for _, e := range set.Entries {
+			list = append(list, s.ipsetEntryItem(set, e))
+		}
+		writeData(w, list)
+	case http.MethodPost:
+		if !rejectDigest(w, r) {
+			return
+		}
+		cidr := r.Form.Get("cidr")
+		if errs := requireParams(r, "cidr"); errs != nil {
+			writeParamError(w, errs)
+			return
+		}
+		if !validCIDR(cidr) {
+			writeParamError(w, map[string]string{"cidr": "value does not look like a valid CIDR network"})
+			return
+		}
+		for _, e := range set.Entries {
+			if e.CIDR == cidr {
+				writeError(w, http.StatusInternalServerError, fmt.Sprintf("entry for '%s' already exists", cidr), nil)
+				return
+			}
+		}
+		set.Entries = append(set.Entries, &ipsetEntry{CIDR: cidr, NoMatch: r.Form.Get("nomatch") == "1", Comment: r.Form.Get("comment")})
+		writeData(w, nil)
+	case http.MethodDelete:
+		if !rejectDigest(w, r) {
+			return
+		}
+		if len(set.Entries) > 0 && r.Form.Get("force") != "1" {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("IPSet '%s' is not empty", set.Name), nil)
+			return
+		}
+		delete(s.ipsets, set.Name)
+		writeData(w, nil)
+	}
+}
+
+func (s *Server) serveIPSetEntry(w http.ResponseWriter, r *http.Request, set *firewallIPSet, cidr string) {
+	idx := -1
+	for i, e := range set.Entries {
+		if e.CIDR == cidr {
+			idx = i
+		}
+	}
+	if idx < 0 {
+		// Proxmox VE reports a missing entry as a parameter error.
+		writeParamError(w, map[string]string{"cidr": "no such IP/Network"})
+		return
+	}
+	entry := set.Entries[idx]
+	if r.Method != http.MethodGet && !checkDigest(w, r, ipsetDigest(set)) {
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		writeData(w, s.ipsetEntryItem(set, entry))
+	case http.MethodPut:
+		if r.Form.Has("nomatch") {
+			entry.NoMatch = r.Form.Get("nomatch") == "1"
+		}
+		if r.Form.Has("comment") {
+			entry.Comment = r.Form.Get("comment")
+		}
+		writeData(w, nil)
+	case http.MethodDelete:
+		set.Entries = append(set.Entries[:idx], set.Entries[idx+1:]...)
+		writeData(w, nil)
+	}
+}
+
+func (s *Server) ipsetEntryItem(set *firewallIPSet, e *ipsetEntry) map[string]any {
+	item := map[string]any{"cidr": e.CIDR, "digest": ipsetDigest(set)}
+	if e.NoMatch {
+		item["nomatch"] = 1
+	}
+	if e.Comment != "" {
+		item["comment"] = e.Comment
+	}
+	return item
+}
+
+func (s *Server) serveGroups(w http.ResponseWriter, r *http.Request, path []string) {
+	switch {
+	case len(path) == 0 && r.Method == http.MethodGet:
+		list := []map[string]any{}
+		for _, name := range sortedKeys(s.groups) {
+			g := s.groups[name]
+			item := map[string]any{"group": g.Name, "digest": s.groupsDigest()}
+			if g.Comment != "" {
+				item["comment"] = g.Comment
+			}
+			list = append(list, item)
+		}
+		writeData(w, list)
+	case len(path) == 0 && r.Method == http.MethodPost:
+		s.createGroup(w, r)
+	case len(path) == 1:
+		g, ok := s.groups[path[0]]
+		if !ok {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("no such security group '%s'", path[0]), nil)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			writeData(w, g.Rules)
+		case http.MethodDelete:
+			if !rejectDigest(w, r) {
+				return
+			}
+			if len(g.Rules) > 0 {
+				writeError(w, http.StatusInternalServerError, fmt.Sprintf("Security group '%s' is not empty", g.Name), nil)
+				return
+			}
+			delete(s.groups, g.Name)
+			writeData(w, nil)
+		default:
+			writeError(w, http.StatusNotImplemented, "Method not implemented", nil)
+		}
+	default:
+		writeError(w, http.StatusNotImplemented, "Method not implemented", nil)
+	}
+}
+
+func (s *Server) createGroup(w http.ResponseWriter, r *http.Request) {
+	name, rename := r.Form.Get("group"), r.Form.Get("rename")
+	if errs := requireParams(r, "group"); errs != nil {
+		writeParamError(w, errs)
+		return
+	}
+	if !checkDigest(w, r, s.groupsDigest()) {
+		return
+	}
+	if rename != "" {
+		g, ok := s.groups[rename]
+		if !ok {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("Security group '%s' does not exist", rename), nil)
+			return
+		}
+		if _, exists := s.groups[name]; exists && name != rename {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("Security group '%s' already exists", name), nil)
+			return
+		}
+		delete(s.groups, rename)
+		g.Name = name
+		g.Comment = r.Form.Get("comment")
+		s.groups[name] = g
+		writeData(w, nil)
+		return
+	}
+	if _, exists := s.groups[name]; exists {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Security group '%s' already exists", name), nil)
+		return
+	}
+	s.groups[name] = &firewallGroup{Name: name, Comment: r.Form.Get("comment"), Rules: []map[string]any{}}
+	writeData(w, nil)
+}
+
+func (s *Server) serveRefs(w http.ResponseWriter, r *http.Request) {
+	refType := r.Form.Get("type")
+	list := []map[string]any{}
+	if refType == "" || refType == "alias" {
+		for _, name := range sortedKeys(s.aliases) {
+			list = append(list, map[string]any{"type": "alias", "name": name, "ref": name, "comment": s.aliases[name].Comment})
+		}
+	}
+	if refType == "" || refType == "ipset" {
+		for _, name := range sortedKeys(s.ipsets) {
+			list = append(list, map[string]any{"type": "ipset", "name": name, "ref": "+" + name, "comment": s.ipsets[name].Comment})
+		}
+	}
+	writeData(w, list)
+}
+
+func requireParams(r *http.Request, keys ...string) map[string]string {
+	errs := map[string]string{}
+	for _, k := range keys {
+		if r.Form.Get(k) == "" {
+			errs[k] = "property is missing and it is not optional"
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func validCIDR(cidr string) bool {
+	if _, _, err := net.ParseCIDR(cidr); err == nil {
+		return true
+	}
+	return net.ParseIP(cidr) != nil
+}
+
+func ipVersion(cidr string) int {
+	if strings.Contains(cidr, ":") {
+		return 6
+	}
+	return 4
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
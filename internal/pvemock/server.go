@@ -0,0 +1,196 @@
+// Package pvemock implements an in-memory, stateful fake of the subset of the
+// Proxmox VE `/api2/json` API used by the provider, so acceptance tests can run
+// without access to a real cluster.
+package pvemock
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+const apiPrefix = "/api2/json"
+
+// Server is a fake Proxmox VE API server backed by httptest.
+type Server struct {
+	*httptest.Server
+
+	// TokenID and Secret are the only API token credentials accepted by the server.
+	TokenID string
+	Secret  string
+
+	// RootPassword is the password accepted for `root@pam` on /access/ticket.
+	// Other users are added with AddUser.
+	RootPassword string
+
+	// TicketLifetime is how long a ticket is accepted after being issued.
+	TicketLifetime time.Duration
+
+	// Node is the name of the single node of the fake cluster.
+	Node string
+
+	// Release and Version are returned by /version.
+	Release string
+	Version string
+
+	mu         sync.Mutex
+	users      map[string]string
+	tfa        map[string]*TFA
+	tickets    map[string]*ticket
+	challenges map[string]string
+	perms      map[string]map[string]int
+	storage    map[string]map[string]string
+	aliases    map[string]*firewallAlias
+	ipsets     map[string]*firewallIPSet
+	groups     map[string]*firewallGroup
+	accounts   map[string]*acmeAccount
+	plugins    map[string]map[string]string
+	tasks      map[string]*task
+}
+
+// New starts a TLS fake server seeded with the storage definitions of a fresh
+// Proxmox VE installation. Call Close when done.
+func New() *Server {
+	s := &Server{
+		TokenID:        "root@pam!pvemock",
+		Secret:         randomHex(16),
+		RootPassword:   "pvemock",
+		Node:           "pve",
+		Release:        "7.3",
+		Version:        "7.3-4",
+		TicketLifetime: 2 * time.Hour,
+		users:          map[string]string{},
+		tfa:            map[string]*TFA{},
+		tickets:        map[string]*ticket{},
+		challenges:     map[string]string{},
+		storage: map[string]map[string]string{
+			"local": {
+				"type":    "dir",
+				"path":    "/var/lib/vz",
+				"content": "iso,vztmpl,backup",
+			},
+			"local-lvm": {
+				"type":     "lvmthin",
+				"vgname":   "pve",
+				"thinpool": "data",
+				"content":  "rootdir,images",
+			},
+		},
+		aliases:  map[string]*firewallAlias{},
+		ipsets:   map[string]*firewallIPSet{},
+		groups:   map[string]*firewallGroup{},
+		accounts: map[string]*acmeAccount{},
+		plugins:  map[string]map[string]string{},
+		tasks:    map[string]*task{},
+	}
+	s.Server = httptest.NewTLSServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.URL.Path, apiPrefix+"/") {
+		writeError(w, http.StatusNotImplemented, "Method not implemented", nil)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, "Parameter verification failed.", nil)
+		return
+	}
+	path := strings.Split(strings.TrimPrefix(r.URL.Path, apiPrefix+"/"), "/")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if path[0] == "access" && len(path) == 2 && path[1] == "ticket" && r.Method == http.MethodPost {
+		s.createTicket(w, r)
+		return
+	}
+	if !s.authenticated(r) {
+		writeError(w, http.StatusUnauthorized, "authentication failure", nil)
+		return
+	}
+
+	switch {
+	case path[0] == "version" && len(path) == 1:
+		writeData(w, map[string]any{
+			"version": s.Version,
+			"release": s.Release,
+			"repoid":  "pvemock",
+		})
+	case path[0] == "access" && len(path) == 2 && path[1] == "permissions" && r.Method == http.MethodGet:
+		s.servePermissions(w)
+	case path[0] == "storage":
+		s.serveStorage(w, r, path[1:])
+	case len(path) >= 2 && path[0] == "cluster" && path[1] == "firewall":
+		s.serveFirewall(w, r, path[2:])
+	case len(path) >= 2 && path[0] == "cluster" && path[1] == "acme":
+		s.serveACME(w, r, path[2:])
+	case len(path) >= 3 && path[0] == "nodes" && path[1] == s.Node && path[2] == "tasks":
+		s.serveTasks(w, r, path[3:])
+	default:
+		writeError(w, http.StatusNotImplemented, fmt.Sprintf("Method '%s /%s' not implemented", r.Method, strings.Join(path, "/")), nil)
+	}
+}
+
+func writeData(w http.ResponseWriter, data any) {
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	_ = json.NewEncoder(w).Encode(map[string]any{"data": data})
+}
+
+// writeError replies the way pveproxy does: the error message is sent as the
+// HTTP reason phrase, and parameter errors are listed under `errors`.
+func writeError(w http.ResponseWriter, code int, message string, errs map[string]string) {
+	body, _ := json.Marshal(struct {
+		Data   any               `json:"data"`
+		Errors map[string]string `json:"errors,omitempty"`
+	}{nil, errs})
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		w.WriteHeader(code)
+		_, _ = w.Write(body)
+		return
+	}
+	conn, buf, err := hijacker.Hijack()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	fmt.Fprintf(buf, "HTTP/1.1 %d %s\r\n", code, message)
+	fmt.Fprintf(buf, "Content-Type: application/json;charset=UTF-8\r\nContent-Length: %d\r\nConnection: close\r\n\r\n", len(body))
+	_, _ = buf.Write(body)
+	_ = buf.Flush()
+}
+
+func writeParamError(w http.ResponseWriter, errs map[string]string) {
+	writeError(w, http.StatusBadRequest, "Parameter verification failed.", errs)
+}
+
+func digest(v any) string {
+	b, _ := json.Marshal(v)
+	sum := sha1.Sum(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func deleteList(r *http.Request) []string {
+	var keys []string
+	for _, k := range strings.Split(r.Form.Get("delete"), ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
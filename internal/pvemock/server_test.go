@@ -0,0 +1,134 @@
+package pvemock
+
+import (
+	"net/url"
+	"testing"
+
+	proxmox "github.com/c10l/proxmoxve-client-go/api"
+	"github.com/c10l/proxmoxve-client-go/api/cluster/firewall/aliases"
+	"github.com/c10l/proxmoxve-client-go/api/cluster/firewall/ipset"
+	"github.com/c10l/proxmoxve-client-go/api/cluster/firewall/ipset/ipset_cidr"
+	"github.com/c10l/proxmoxve-client-go/api/storage"
+	"github.com/c10l/proxmoxve-client-go/helpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClient(t *testing.T) (*Server, *proxmox.Client) {
+	s := New()
+	t.Cleanup(s.Close)
+	client, err := proxmox.NewAPITokenClient(s.URL, s.TokenID, s.Secret, true)
+	require.NoError(t, err)
+	return s, client
+}
+
+func TestAuthentication(t *testing.T) {
+	s, _ := newTestClient(t)
+
+	client, err := proxmox.NewAPITokenClient(s.URL, s.TokenID, "wrong", true)
+	require.NoError(t, err)
+	_, err = storage.GetRequest{Client: client}.Get()
+	assert.ErrorContains(t, err, "401 authentication failure")
+
+	client, err = proxmox.NewTicketClient(s.URL, "root@pam", s.RootPassword, "", true)
+	require.NoError(t, err)
+	_, err = storage.GetRequest{Client: client}.Get()
+	assert.NoError(t, err)
+
+	_, err = proxmox.NewTicketClient(s.URL, "root@pam", "wrong", "", true)
+	assert.Error(t, err)
+
+	s.AddUser("terraform@pve", "hunter2")
+	client, err = proxmox.NewTicketClient(s.URL, "terraform@pve", "hunter2", "", true)
+	require.NoError(t, err)
+	_, err = storage.GetRequest{Client: client}.Get()
+	assert.NoError(t, err)
+}
+
+func TestStorage(t *testing.T) {
+	_, client := newTestClient(t)
+
+	local, err := storage.ItemGetRequest{Client: client, Storage: "local"}.Get()
+	require.NoError(t, err)
+	assert.Equal(t, "/var/lib/vz", local.Path)
+	assert.Equal(t, []string{"backup", "iso", "vztmpl"}, local.Content)
+
+	_, err = storage.PostRequest{
+		Client:      client,
+		Storage:     "nfs",
+		StorageType: storage.TypeNFS,
+		NFSServer:   helpers.PtrTo("10.0.0.1"),
+		NFSExport:   helpers.PtrTo("/export"),
+		Nodes:       &[]string{"pve"},
+	}.Post()
+	require.NoError(t, err)
+
+	item, err := storage.ItemGetRequest{Client: client, Storage: "nfs"}.Get()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"images"}, item.Content)
+	assert.Equal(t, []string{"pve"}, item.Nodes)
+
+	_, err = storage.ItemPutRequest{Client: client, Storage: "nfs", NFSMountOptions: helpers.PtrTo("vers=4.2")}.Put()
+	require.NoError(t, err)
+
+	require.NoError(t, storage.ItemDeleteRequest{Client: client, Storage: "nfs"}.Delete())
+	_, err = storage.ItemGetRequest{Client: client, Storage: "nfs"}.Get()
+	assert.ErrorContains(t, err, "500 storage 'nfs' does not exist")
+
+	_, err = storage.PostRequest{Client: client, Storage: "dir", StorageType: storage.TypeDir}.Post()
+	assert.ErrorContains(t, err, "400 Parameter verification failed.")
+	assert.ErrorContains(t, err, `"path":"property is missing and it is not optional"`)
+}
+
+func TestFirewallIPSet(t *testing.T) {
+	_, client := newTestClient(t)
+
+	require.NoError(t, ipset.PostRequest{Client: client, Name: "set"}.Post())
+	require.NoError(t, ipset_cidr.PostRequest{Client: client, IPSetName: "set", CIDR: "10.0.0.0/8"}.Post())
+
+	entry, err := ipset_cidr.ItemGetRequest{Client: client, IPSetName: "set", CIDR: "10.0.0.0/8"}.Get()
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.0/8", entry.CIDR)
+	assert.Nil(t, entry.Comment)
+
+	assert.ErrorContains(t, ipset.ItemDeleteRequest{Client: client, Name: "set"}.Delete(), "IPSet 'set' is not empty")
+	require.NoError(t, ipset.ItemDeleteRequest{Client: client, Name: "set"}.ForceDelete())
+
+	_, err = ipset_cidr.ItemGetRequest{Client: client, IPSetName: "set", CIDR: "10.0.0.0/8"}.Get()
+	assert.ErrorContains(t, err, "no such IPSet 'set'")
+}
+
+func TestFirewallDigest(t *testing.T) {
+	_, client := newTestClient(t)
+
+	require.NoError(t, aliases.PostRequest{Client: client, Name: "a", CIDR: "10.0.0.1"}.Post())
+	list, err := aliases.GetRequest{Client: client}.Get()
+	require.NoError(t, err)
+	digest := list[0].Digest
+
+	require.NoError(t, aliases.PostRequest{Client: client, Name: "b", CIDR: "10.0.0.2"}.Post())
+	err = aliases.ItemPutRequest{Client: client, Name: "a", CIDR: "10.0.0.3", Digest: &digest}.Put()
+	assert.ErrorContains(t, err, "detected modified configuration")
+
+	list, err = aliases.GetRequest{Client: client}.Get()
+	require.NoError(t, err)
+	require.NoError(t, aliases.ItemPutRequest{Client: client, Name: "a", CIDR: "10.0.0.3", Digest: &list[0].Digest}.Put())
+
+	// Every list has its own digest: changing another one keeps it.
+	list, err = aliases.GetRequest{Client: client}.Get()
+	require.NoError(t, err)
+	require.NoError(t, ipset.PostRequest{Client: client, Name: "set"}.Post())
+	require.NoError(t, ipset_cidr.PostRequest{Client: client, IPSetName: "set", CIDR: "10.0.0.0/8"}.Post())
+	entries, err := ipset_cidr.GetRequest{Client: client, IPSetName: "set"}.Get()
+	require.NoError(t, err)
+	assert.NotEqual(t, list[0].Digest, entries[0].Digest)
+	require.NoError(t, aliases.ItemPutRequest{Client: client, Name: "a", CIDR: "10.0.0.4", Digest: &list[0].Digest}.Put())
+	require.NoError(t, ipset_cidr.ItemPutRequest{Client: client, IPSetName: "set", CIDR: "10.0.0.0/8", Digest: &entries[0].Digest}.Put())
+
+	// The creations of aliases and IPSet entries take no digest.
+	aliasesURL := client.APIurl
+	aliasesURL.Path += "/cluster/firewall/aliases"
+	aliasesURL.RawQuery = url.Values{"name": {"c"}, "cidr": {"10.0.0.5"}, "digest": {list[0].Digest}}.Encode()
+	_, err = client.Post(&aliasesURL)
+	assert.ErrorContains(t, err, "400 Parameter verification failed.")
+}
@@ -0,0 +1,312 @@
+package pvemock
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// storagePlugin describes the options accepted by a storage type.
+type storagePlugin struct {
+	required       []string
+	options        []string
+	fixed          []string
+	content        []string
+	defaultContent string
+}
+
+var storageCommonOptions = []string{"content", "nodes", "disable", "prune-backups", "shared"}
+
+var storagePlugins = map[string]storagePlugin{
+	"dir": {
+		required:       []string{"path"},
+		options:        []string{"path", "preallocation", "mkdir", "is_mountpoint", "content-dirs"},
+		fixed:          []string{"path"},
+		content:        []string{"images", "rootdir", "vztmpl", "iso", "backup", "snippets"},
+		defaultContent: "images,rootdir",
+	},
+	"btrfs": {
+		required:       []string{"path"},
+		options:        []string{"path", "preallocation", "is_mountpoint"},
+		fixed:          []string{"path"},
+		content:        []string{"images", "rootdir", "vztmpl", "iso", "backup", "snippets"},
+		defaultContent: "images,rootdir",
+	},
+	"nfs": {
+		required:       []string{"server", "export"},
+		options:        []string{"server", "export", "options", "preallocation", "mkdir"},
+		fixed:          []string{"server", "export"},
+		content:        []string{"images", "rootdir", "vztmpl", "iso", "backup", "snippets"},
+		defaultContent: "images",
+	},
+	"cifs": {
+		required:       []string{"server", "share"},
+		options:        []string{"server", "share", "username", "password", "domain", "smbversion", "subdir", "options", "preallocation", "mkdir"},
+		fixed:          []string{"server", "share"},
+		content:        []string{"images", "rootdir", "vztmpl", "iso", "backup", "snippets"},
+		defaultContent: "images",
+	},
+	"lvm": {
+		required:       []string{"vgname"},
+		options:        []string{"vgname", "base", "saferemove", "saferemove_throughput", "tagged_only"},
+		fixed:          []string{"vgname", "base"},
+		content:        []string{"images", "rootdir"},
+		defaultContent: "images,rootdir",
+	},
+	"lvmthin": {
+		required:       []string{"vgname", "thinpool"},
+		options:        []string{"vgname", "thinpool"},
+		fixed:          []string{"vgname", "thinpool"},
+		content:        []string{"images", "rootdir"},
+		defaultContent: "images,rootdir",
+	},
+	"zfspool": {
+		required:       []string{"pool"},
+		options:        []string{"pool", "blocksize", "sparse", "mountpoint"},
+		fixed:          []string{"pool"},
+		content:        []string{"images", "rootdir"},
+		defaultContent: "images,rootdir",
+	},
+	"pbs": {
+		required:       []string{"server", "datastore"},
+		options:        []string{"server", "port", "datastore", "username", "password", "namespace", "fingerprint", "encryption-key", "master-pubkey"},
+		fixed:          []string{"datastore"},
+		content:        []string{"backup"},
+		defaultContent: "backup",
+	},
+}
+
+// storageIntOptions are returned as JSON numbers rather than strings.
+var storageIntOptions = map[string]bool{
+	"disable": true, "shared": true, "saferemove": true, "tagged_only": true,
+	"sparse": true, "port": true, "mkdir": true,
+}
+
+// storageSecretOptions are kept out of storage.cfg and never returned, though
+// whether the keys of a pbs storage are set is.
+var storageSecretOptions = map[string]bool{"password": true, "encryption-key": true, "master-pubkey": true}
+
+func (s *Server) serveStorage(w http.ResponseWriter, r *http.Request, path []string) {
+	switch {
+	case len(path) == 0 && r.Method == http.MethodGet:
+		s.listStorage(w, r)
+	case len(path) == 0 && r.Method == http.MethodPost:
+		s.createStorage(w, r)
+	case len(path) == 1 && r.Method == http.MethodGet:
+		if cfg := s.findStorage(w, path[0]); cfg != nil {
+			writeData(w, s.storageItem(path[0], cfg))
+		}
+	case len(path) == 1 && r.Method == http.MethodPut:
+		s.updateStorage(w, r, path[0])
+	case len(path) == 1 && r.Method == http.MethodDelete:
+		if cfg := s.findStorage(w, path[0]); cfg != nil {
+			delete(s.storage, path[0])
+			writeData(w, nil)
+		}
+	default:
+		writeError(w, http.StatusNotImplemented, "Method not implemented", nil)
+	}
+}
+
+// encryptionKeyFingerprint returns the fingerprint of the encryption key of a
+// pbs storage, or "1" if it has none.
+func encryptionKeyFingerprint(key string) string {
+	var decoded struct {
+		Fingerprint string `json:"fingerprint"`
+	}
+	if json.Unmarshal([]byte(key), &decoded) != nil || decoded.Fingerprint == "" {
+		return "1"
+	}
+	return decoded.Fingerprint
+}
+
+// StorageSecret returns the secret option of the storage name last set, e.g.
+// its password, which the API never returns. It is empty if unset.
+func (s *Server) StorageSecret(name, option string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.storage[name][option]
+}
+
+// SetStorageOption sets an option of the storage name, as if it was changed
+// outside of the provider.
+func (s *Server) SetStorageOption(name, option, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.storage[name][option] = value
+}
+
+func (s *Server) findStorage(w http.ResponseWriter, name string) map[string]string {
+	cfg, ok := s.storage[name]
+	if !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("storage '%s' does not exist", name), nil)
+		return nil
+	}
+	return cfg
+}
+
+func (s *Server) storageItem(name string, cfg map[string]string) map[string]any {
+	item := map[string]any{"storage": name, "digest": digest(s.storage)}
+	for k, v := range cfg {
+		if storageSecretOptions[k] {
+			// Only whether the keys are set is kept in storage.cfg.
+			switch k {
+			case "encryption-key":
+				item[k] = encryptionKeyFingerprint(v)
+			case "master-pubkey":
+				item[k] = 1
+			}
+			continue
+		}
+		if storageIntOptions[k] {
+			n, _ := strconv.Atoi(v)
+			item[k] = n
+			continue
+		}
+		item[k] = v
+	}
+	return item
+}
+
+func (s *Server) listStorage(w http.ResponseWriter, r *http.Request) {
+	names := make([]string, 0, len(s.storage))
+	for name, cfg := range s.storage {
+		if t := r.Form.Get("type"); t != "" && cfg["type"] != t {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	list := make([]map[string]any, 0, len(names))
+	for _, name := range names {
+		list = append(list, s.storageItem(name, s.storage[name]))
+	}
+	writeData(w, list)
+}
+
+func (s *Server) createStorage(w http.ResponseWriter, r *http.Request) {
+	name, storageType := r.Form.Get("storage"), r.Form.Get("type")
+	plugin, ok := storagePlugins[storageType]
+	if !ok {
+		writeParamError(w, map[string]string{"type": fmt.Sprintf("value '%s' does not have a value in the enumeration", storageType)})
+		return
+	}
+	if _, exists := s.storage[name]; exists {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("create storage failed: storage ID '%s' already defined", name), nil)
+		return
+	}
+
+	errs := map[string]string{}
+	if name == "" {
+		errs["storage"] = "property is missing and it is not optional"
+	}
+	for _, k := range plugin.required {
+		if r.Form.Get(k) == "" {
+			errs[k] = "property is missing and it is not optional"
+		}
+	}
+	cfg := map[string]string{"type": storageType, "content": plugin.defaultContent}
+	s.applyStorageOptions(r, plugin, cfg, nil, errs)
+	if len(errs) > 0 {
+		writeParamError(w, errs)
+		return
+	}
+
+	s.storage[name] = cfg
+	writeData(w, storageResult(name, cfg))
+}
+
+func (s *Server) updateStorage(w http.ResponseWriter, r *http.Request, name string) {
+	cfg := s.findStorage(w, name)
+	if cfg == nil {
+		return
+	}
+	plugin := storagePlugins[cfg["type"]]
+
+	updated := map[string]string{}
+	for k, v := range cfg {
+		updated[k] = v
+	}
+	errs := map[string]string{}
+	s.applyStorageOptions(r, plugin, updated, plugin.fixed, errs)
+	for _, k := range deleteList(r) {
+		delete(updated, k)
+	}
+	if len(errs) > 0 {
+		writeParamError(w, errs)
+		return
+	}
+
+	s.storage[name] = updated
+	writeData(w, storageResult(name, updated))
+}
+
+// applyStorageOptions copies the request parameters known to plugin into cfg,
+// recording a parameter error for unknown, fixed or invalid values.
+func (s *Server) applyStorageOptions(r *http.Request, plugin storagePlugin, cfg map[string]string, fixed []string, errs map[string]string) {
+	allowed := map[string]bool{}
+	for _, k := range append(append([]string{}, storageCommonOptions...), plugin.options...) {
+		allowed[k] = true
+	}
+	for _, k := range fixed {
+		allowed[k] = false
+	}
+	for k, v := range r.Form {
+		switch {
+		case k == "storage" || k == "type" || k == "digest" || k == "delete":
+			continue
+		case !allowed[k]:
+			errs[k] = "property is not defined in schema and the schema does not allow additional properties"
+			continue
+		case k == "encryption-key" && v[0] != "autogen" && !json.Valid([]byte(v[0])):
+			errs[k] = "failed to parse encryption key"
+			continue
+		case k == "content":
+			if bad := invalidContent(v[0], plugin.content); bad != "" {
+				errs[k] = fmt.Sprintf("invalid content type '%s'", bad)
+				continue
+			}
+		}
+		cfg[k] = v[0]
+	}
+}
+
+// storageResult returns the response to the creation or the update of the
+// storage name, generating the encryption key requested with `autogen`.
+func storageResult(name string, cfg map[string]string) map[string]any {
+	result := map[string]any{"storage": name, "type": cfg["type"]}
+	if cfg["encryption-key"] == "autogen" {
+		key, _ := json.Marshal(map[string]any{
+			"kdf":         nil,
+			"created":     time.Now().Format(time.RFC3339),
+			"modified":    time.Now().Format(time.RFC3339),
+			"data":        randomHex(32),
+			"fingerprint": randomHex(32),
+		})
+		cfg["encryption-key"] = string(key)
+		result["config"] = map[string]string{"encryption-key": string(key)}
+	}
+	return result
+}
+
+func invalidContent(content string, supported []string) string {
+	for _, c := range strings.Split(content, ",") {
+		if c == "" || c == "none" {
+			continue
+		}
+		found := false
+		for _, sc := range supported {
+			if c == sc {
+				found = true
+			}
+		}
+		if !found {
+			return c
+		}
+	}
+	return ""
+}
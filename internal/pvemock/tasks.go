@@ -0,0 +1,53 @@
+package pvemock
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// task is a finished background task. The fake server runs every task
+// synchronously, so they are never seen running.
+type task struct {
+	exitStatus string
+	log        []string
+}
+
+// startTask records a successful task and returns its UPID, in the format used
+// by Proxmox VE.
+func (s *Server) startTask(taskType, id string, log ...string) string {
+	upid := fmt.Sprintf("UPID:%s:%s:%s:%s:%s:%s:root@pam:", s.Node, randomHex(4), randomHex(4), randomHex(4), taskType, id)
+	s.tasks[upid] = &task{exitStatus: "OK", log: log}
+	return upid
+}
+
+func (s *Server) serveTasks(w http.ResponseWriter, r *http.Request, path []string) {
+	if len(path) != 2 || r.Method != http.MethodGet {
+		writeError(w, http.StatusNotImplemented, "Method not implemented", nil)
+		return
+	}
+	t, ok := s.tasks[path[0]]
+	if !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("no such task '%s'", path[0]), nil)
+		return
+	}
+
+	switch path[1] {
+	case "status":
+		writeData(w, map[string]any{
+			"upid":       path[0],
+			"node":       s.Node,
+			"status":     "stopped",
+			"exitstatus": t.exitStatus,
+		})
+	case "log":
+		start, _ := strconv.Atoi(r.Form.Get("start"))
+		lines := []map[string]any{}
+		for i := start; i < len(t.log); i++ {
+			lines = append(lines, map[string]any{"n": i + 1, "t": t.log[i]})
+		}
+		writeData(w, lines)
+	default:
+		writeError(w, http.StatusNotImplemented, "Method not implemented", nil)
+	}
+}